@@ -0,0 +1,228 @@
+package analysis
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+// CounterfactualConfig are the parameters of the simulated boat
+type CounterfactualConfig struct {
+	// Step is the simulation time step
+	Step time.Duration
+	// TackDuration is how long a tack or gybe takes, the boat sails at TackSpeedFactor of its
+	// speed during it
+	TackDuration    time.Duration
+	TackSpeedFactor float64
+	// HeaderThreshold is how many degrees the wind has to head the boat from the best
+	// direction on the tack before the automatic tack
+	HeaderThreshold float64
+}
+
+func DefaultCounterfactualConfig() CounterfactualConfig {
+	return CounterfactualConfig{
+		Step:            time.Second,
+		TackDuration:    10 * time.Second,
+		TackSpeedFactor: 0.5,
+		HeaderThreshold: 5,
+	}
+}
+
+// Counterfactual is a simulated boat that is branched from a point of the log and sails the
+// optimal upwind or downwind angle in the logged wind from then on. It tacks when told to,
+// on the layline and, with AutoTack, on every header. The time it reaches the mark is
+// compared to when the logged boat reached it. The mark is reached when the boat crosses the
+// line through the mark square to the wind at the branch point.
+type Counterfactual struct {
+	config     CounterfactualConfig
+	points     []datasource.NavigationDataPoint
+	polar      *Polar
+	projection datasource.LocalProjection
+	markX      float64
+	markY      float64
+	// axis is the wind direction at the branch point that the progress is measured along
+	axis   float64
+	upwind bool
+	index  int
+	x      float64
+	y      float64
+
+	Time      time.Time
+	Starboard bool
+	AutoTack  bool
+	Tacks     int
+	// Heading is the true heading of the boat
+	Heading float64
+	// reference is the wind direction that headers are measured from
+	reference float64
+	tackEnd   time.Time
+
+	Arrived     bool
+	ArrivalTime time.Time
+	// ActualArrived and ActualArrivalTime tell when the logged boat reached the mark
+	ActualArrived     bool
+	ActualArrivalTime time.Time
+}
+
+// NewCounterfactual branches a simulated boat from the point of the log. The polar can be nil,
+// then the boat sails the default angles at the logged boat speed.
+func NewCounterfactual(points []datasource.NavigationDataPoint, index int, markLat, markLng float64, polar *Polar, config CounterfactualConfig) *Counterfactual {
+	p := points[index]
+	c := &Counterfactual{
+		config:     config,
+		points:     points,
+		polar:      polar,
+		projection: datasource.NewLocalProjection(p.Latitude, p.Longitude),
+		axis:       p.TrueWindDirection,
+		upwind:     math.Abs(p.TrueWindAngle) < 90,
+		index:      index,
+		Time:       p.Timestamp,
+		Starboard:  p.TrueWindAngle >= 0,
+		reference:  p.TrueWindDirection,
+	}
+	c.markX, c.markY = c.projection.ToMeters(markLat, markLng)
+	c.Heading = c.heading(p)
+
+	for _, q := range points[index:] {
+		x, y := c.projection.ToMeters(q.Latitude, q.Longitude)
+		if c.reached(x, y) {
+			c.ActualArrived = true
+			c.ActualArrivalTime = q.Timestamp
+			break
+		}
+	}
+	c.Arrived = c.reached(0, 0)
+	if c.Arrived {
+		c.ArrivalTime = c.Time
+	}
+
+	return c
+}
+
+// Location returns the latitude and longitude of the simulated boat
+func (c *Counterfactual) Location() (float64, float64) {
+	return c.projection.ToLatLng(c.x, c.y)
+}
+
+// Finished returns true when the boat has reached the mark or the log has ended
+func (c *Counterfactual) Finished() bool {
+	return c.Arrived || !c.Time.Before(c.points[len(c.points)-1].Timestamp)
+}
+
+// DistanceToMark returns the distance from the simulated boat to the mark in metres
+func (c *Counterfactual) DistanceToMark() float64 {
+	return math.Hypot(c.markX-c.x, c.markY-c.y)
+}
+
+// Tack puts the boat on the other tack, or gybe when sailing downwind
+func (c *Counterfactual) Tack() {
+	if c.Finished() {
+		return
+	}
+	c.Starboard = !c.Starboard
+	c.Tacks++
+	c.tackEnd = c.Time.Add(c.config.TackDuration)
+	c.reference = c.points[c.index].TrueWindDirection
+}
+
+// AdvanceTo sails the boat until the time in simulation steps
+func (c *Counterfactual) AdvanceTo(t time.Time) {
+	for !c.Finished() && c.Time.Before(t) {
+		step := min(c.config.Step, t.Sub(c.Time))
+		c.index = max(c.index, sort.Search(len(c.points), func(i int) bool {
+			return c.points[i].Timestamp.After(c.Time)
+		})-1)
+		p := c.points[c.index]
+
+		if !c.Time.Before(c.tackEnd) && (c.onLayline(p) || c.AutoTack && c.headed(p)) {
+			c.Tack()
+		}
+
+		c.Heading = c.heading(p)
+		speed := c.speed(p) * datasource.MetersPerNauticalMile / 3600
+		if c.Time.Before(c.tackEnd) {
+			speed *= c.config.TackSpeedFactor
+		}
+		distance := speed * step.Seconds()
+		c.x += distance * math.Sin(toRadians(c.Heading))
+		c.y += distance * math.Cos(toRadians(c.Heading))
+		c.Time = c.Time.Add(step)
+
+		if c.reached(c.x, c.y) {
+			c.Arrived = true
+			c.ArrivalTime = c.Time
+		}
+	}
+}
+
+// twa returns the signed true wind angle that the boat sails in the wind, positive on starboard
+func (c *Counterfactual) twa(p datasource.NavigationDataPoint) float64 {
+	beat, run := DefaultBeatAngle, DefaultRunAngle
+	if c.polar != nil {
+		beat, run = c.polar.OptimalAngles(p.TrueWindSpeed)
+	}
+
+	twa := run
+	if c.upwind {
+		twa = beat
+	}
+	if !c.Starboard {
+		twa = -twa
+	}
+	return twa
+}
+
+func (c *Counterfactual) heading(p datasource.NavigationDataPoint) float64 {
+	return datasource.NormalizeDirection(p.TrueWindDirection - c.twa(p))
+}
+
+// speed is the polar target at the sailed angle, or the logged boat speed without a polar
+func (c *Counterfactual) speed(p datasource.NavigationDataPoint) float64 {
+	if c.polar != nil {
+		return c.polar.TargetSpeed(c.twa(p), p.TrueWindSpeed)
+	}
+	return boatSpeed(p)
+}
+
+// onLayline returns true when the mark can be laid on the other tack
+func (c *Counterfactual) onLayline(p datasource.NavigationDataPoint) bool {
+	bearing := math.Atan2(c.markX-c.x, c.markY-c.y) * 180 / math.Pi
+	markAngle := datasource.NormalizeAngle(bearing - p.TrueWindDirection)
+	twa := c.twa(p)
+
+	// The other tack sails at the opposite side of the wind at the same angle
+	if markAngle*twa <= 0 {
+		return false
+	}
+	if c.upwind {
+		return math.Abs(markAngle) >= math.Abs(twa)
+	}
+	return math.Abs(markAngle) <= math.Abs(twa)
+}
+
+// headed returns true when the wind has shifted against the boat from the best direction on
+// the tack. Upwind the boat is headed when the wind turns towards the bow, downwind when it
+// turns towards the stern.
+func (c *Counterfactual) headed(p datasource.NavigationDataPoint) bool {
+	shift := datasource.NormalizeAngle(p.TrueWindDirection - c.reference)
+	if c.Starboard == c.upwind {
+		shift = -shift
+	}
+	if shift < 0 {
+		c.reference = p.TrueWindDirection
+	}
+	return shift > c.config.HeaderThreshold
+}
+
+// reached returns true when the location is past the mark along the wind
+func (c *Counterfactual) reached(x, y float64) bool {
+	progress := func(x, y float64) float64 {
+		return x*math.Sin(toRadians(c.axis)) + y*math.Cos(toRadians(c.axis))
+	}
+	if c.upwind {
+		return progress(x, y) >= progress(c.markX, c.markY)
+	}
+	return progress(x, y) <= progress(c.markX, c.markY)
+}
@@ -0,0 +1,70 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+func TestCounterfactual(t *testing.T) {
+	require := require.New(t)
+
+	points := trackPoints(59.0, 24.0, segment{45, 10 * time.Minute})
+	start := points[0].Timestamp
+	markLat, markLng := datasource.NewLocalProjection(59.0, 24.0).ToLatLng(0, 1000)
+
+	c := NewCounterfactual(points, 0, markLat, markLng, nil, DefaultCounterfactualConfig())
+	require.True(c.Starboard)
+	require.InDelta(315.0, c.Heading, 0.001)
+
+	// The logged boat sails on past the layline and crosses the mark's wind line first
+	require.True(c.ActualArrived)
+	require.Equal(458*time.Second, c.ActualArrivalTime.Sub(start))
+
+	// The simulated boat tacks on the layline, fetches the mark and loses time in the tack
+	c.AdvanceTo(points[len(points)-1].Timestamp)
+	require.True(c.Arrived)
+	require.Equal(1, c.Tacks)
+	require.False(c.Starboard)
+	require.InDelta(45.0, c.Heading, 0.001)
+	require.Less(c.DistanceToMark(), 20.0)
+	require.InDelta(463, c.ArrivalTime.Sub(start).Seconds(), 2)
+
+	// Tacking more loses more
+	c = NewCounterfactual(points, 0, markLat, markLng, nil, DefaultCounterfactualConfig())
+	c.AdvanceTo(start.Add(60 * time.Second))
+	c.Tack()
+	c.AdvanceTo(start.Add(120 * time.Second))
+	c.Tack()
+	c.AdvanceTo(points[len(points)-1].Timestamp)
+	require.True(c.Arrived)
+	require.Equal(3, c.Tacks)
+	require.InDelta(473, c.ArrivalTime.Sub(start).Seconds(), 2)
+}
+
+func TestCounterfactualAutoTack(t *testing.T) {
+	require := require.New(t)
+
+	points := trackPoints(59.0, 24.0, segment{45, 10 * time.Minute})
+	start := points[0].Timestamp
+	markLat, markLng := datasource.NewLocalProjection(59.0, 24.0).ToLatLng(0, 1000)
+
+	// The wind backs by 10 degrees after 100 seconds, heading the boat on starboard
+	for i := 100; i < len(points); i++ {
+		points[i].TrueWindDirection = 350
+	}
+
+	c := NewCounterfactual(points, 0, markLat, markLng, nil, DefaultCounterfactualConfig())
+	c.AdvanceTo(start.Add(105 * time.Second))
+	require.Equal(0, c.Tacks)
+	require.InDelta(305.0, c.Heading, 0.001)
+
+	c = NewCounterfactual(points, 0, markLat, markLng, nil, DefaultCounterfactualConfig())
+	c.AutoTack = true
+	c.AdvanceTo(start.Add(105 * time.Second))
+	require.Equal(1, c.Tacks)
+	require.False(c.Starboard)
+	require.InDelta(35.0, c.Heading, 0.001)
+}
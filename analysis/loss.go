@@ -0,0 +1,187 @@
+package analysis
+
+import (
+	"math"
+	"time"
+
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+// ManeuverLoss is the cost of a tack or gybe compared to sailing on at the pre-maneuver VMG
+type ManeuverLoss struct {
+	Event Event
+	// TurnStart and TurnEnd are when the course started to change and when it settled on the
+	// new course
+	TurnStart time.Time
+	TurnEnd   time.Time
+	// EntrySpeed, ExitSpeed and MinSpeed are the boat speeds in knots at the start and end of
+	// the turn and the lowest speed during the maneuver
+	EntrySpeed float64
+	ExitSpeed  float64
+	MinSpeed   float64
+	// TargetSpeed is the polar target on the new tack, or the entry speed without a polar
+	TargetSpeed float64
+	// RecoveryTime is the time from the end of the turn until the target speed was reached,
+	// Recovered is false if it wasn't reached before the next event
+	RecoveryTime time.Duration
+	Recovered    bool
+	// MetersLost is the distance lost to windward (or leeward when gybing) during the loss window
+	MetersLost float64
+}
+
+// TurnDuration returns how long the boat was turning
+func (ml ManeuverLoss) TurnDuration() time.Duration {
+	return ml.TurnEnd.Sub(ml.TurnStart)
+}
+
+// LossConfig are the parameters of the maneuver loss computation
+type LossConfig struct {
+	// CourseWindow is the time before and after the turn that the entry and exit courses are
+	// averaged over, CourseMargin is skipped next to the event itself
+	CourseWindow time.Duration
+	CourseMargin time.Duration
+	// CourseTolerance is how many degrees the course can differ from the entry or exit course
+	// while not turning
+	CourseTolerance float64
+	// VMGWindow is the time before the turn that the pre-maneuver VMG is averaged over
+	VMGWindow time.Duration
+	// LossWindow is the time from the start of the turn that the distance lost is measured over
+	LossWindow time.Duration
+	// RecoveryFraction is the part of the target speed that counts as recovered
+	RecoveryFraction float64
+}
+
+func DefaultLossConfig() LossConfig {
+	return LossConfig{
+		CourseWindow:     20 * time.Second,
+		CourseMargin:     20 * time.Second,
+		CourseTolerance:  10,
+		VMGWindow:        30 * time.Second,
+		LossWindow:       60 * time.Second,
+		RecoveryFraction: 0.95,
+	}
+}
+
+// ManeuverLosses computes the losses of the tacks and gybes among the events. The polar can be
+// nil, then the entry speed is used as the target speed. The measurements of a maneuver stop
+// at the next event.
+func ManeuverLosses(points []datasource.NavigationDataPoint, events []Event, polar *Polar, config LossConfig) []ManeuverLoss {
+	var losses []ManeuverLoss
+	for i, e := range events {
+		if !e.IsManeuver() {
+			continue
+		}
+
+		previous, limit := 0, len(points)-1
+		if i > 0 {
+			previous = events[i-1].Index
+		}
+		if i+1 < len(events) {
+			limit = events[i+1].Index
+		}
+		losses = append(losses, maneuverLoss(points, e, previous, limit, polar, config))
+	}
+	return losses
+}
+
+func maneuverLoss(points []datasource.NavigationDataPoint, e Event, previous, limit int, polar *Polar, config LossConfig) ManeuverLoss {
+	ml := ManeuverLoss{Event: e}
+
+	// The courses are averaged away from the turn, but not past the events around it
+	entryCourse := meanCourse(points[previous:e.Index+1],
+		e.Timestamp.Add(-config.CourseMargin-config.CourseWindow), e.Timestamp.Add(-config.CourseMargin))
+	exitCourse := meanCourse(points[e.Index:max(e.Index+1, limit)],
+		e.Timestamp.Add(config.CourseMargin), e.Timestamp.Add(config.CourseMargin+config.CourseWindow))
+
+	start := e.Index
+	for start > previous && math.Abs(datasource.NormalizeAngle(points[start].CourseOverGround-entryCourse)) > config.CourseTolerance {
+		start--
+	}
+	end := e.Index
+	for end < limit && math.Abs(datasource.NormalizeAngle(points[end].CourseOverGround-exitCourse)) > config.CourseTolerance {
+		end++
+	}
+
+	ml.TurnStart = points[start].Timestamp
+	ml.TurnEnd = points[end].Timestamp
+	ml.EntrySpeed = boatSpeed(points[start])
+	ml.ExitSpeed = boatSpeed(points[end])
+
+	ml.TargetSpeed = ml.EntrySpeed
+	if polar != nil {
+		ml.TargetSpeed = polar.TargetSpeed(points[end].TrueWindAngle, points[end].TrueWindSpeed)
+	}
+
+	ml.MinSpeed = ml.EntrySpeed
+	recovered := -1
+	for i := start; i <= limit; i++ {
+		speed := boatSpeed(points[i])
+		if i >= end && speed >= ml.TargetSpeed*config.RecoveryFraction {
+			recovered = i
+			break
+		}
+		ml.MinSpeed = min(ml.MinSpeed, speed)
+	}
+	if recovered >= 0 {
+		ml.Recovered = true
+		ml.RecoveryTime = points[recovered].Timestamp.Sub(ml.TurnEnd)
+	}
+
+	// Progress along the wind axis compared to sailing on at the VMG before the turn
+	var vmgSum float64
+	var vmgCount int
+	for i := start; i >= 0 && !points[i].Timestamp.Before(ml.TurnStart.Add(-config.VMGWindow)); i-- {
+		vmgSum += vmg(points[i])
+		vmgCount++
+	}
+	entryVMG := vmgSum / float64(max(1, vmgCount))
+	direction := 1.0
+	if entryVMG < 0 {
+		direction = -1
+	}
+
+	lossEnd := ml.TurnStart.Add(config.LossWindow)
+	var made float64
+	i := start
+	for ; i < limit-1 && points[i+1].Timestamp.Before(lossEnd); i++ {
+		dt := points[i+1].Timestamp.Sub(points[i].Timestamp).Hours()
+		made += (vmg(points[i]) + vmg(points[i+1])) / 2 * dt
+	}
+	expected := math.Abs(entryVMG) * points[i].Timestamp.Sub(ml.TurnStart).Hours()
+	ml.MetersLost = (expected - made*direction) * datasource.MetersPerNauticalMile
+
+	return ml
+}
+
+// meanCourse returns the circular mean of COG over the time range. If the points don't reach
+// the range, the course of the point closest to it is used.
+func meanCourse(points []datasource.NavigationDataPoint, from, to time.Time) float64 {
+	var courses []float64
+	for _, p := range points {
+		if !p.Timestamp.Before(from) && !p.Timestamp.After(to) {
+			courses = append(courses, p.CourseOverGround)
+		}
+	}
+
+	if len(courses) == 0 {
+		closest := points[0]
+		if points[len(points)-1].Timestamp.Before(from) {
+			closest = points[len(points)-1]
+		}
+		return closest.CourseOverGround
+	}
+	return datasource.NormalizeDirection(datasource.MeanAngle(courses))
+}
+
+// boatSpeed is the speed through water, or the speed over ground if there's no log
+func boatSpeed(p datasource.NavigationDataPoint) float64 {
+	if p.SpeedThroughWater > 0 {
+		return p.SpeedThroughWater
+	}
+	return p.SpeedOverGround
+}
+
+// vmg is the velocity made good to wind in knots
+func vmg(p datasource.NavigationDataPoint) float64 {
+	return p.SpeedOverGround * math.Cos(toRadians(p.CourseOverGround-p.TrueWindDirection))
+}
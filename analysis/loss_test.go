@@ -0,0 +1,88 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+// tackPoints returns a point per second of a tack from starboard to port in a northerly wind.
+// The turn takes turnSeconds and the speed drops to minSpeed and recovers linearly over
+// recoverySeconds after the turn.
+func tackPoints(turnSeconds, recoverySeconds int, minSpeed float64) ([]datasource.NavigationDataPoint, int) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	var points []datasource.NavigationDataPoint
+	add := func(cog, speed float64) {
+		twa := datasource.NormalizeAngle(0 - cog)
+		points = append(points, datasource.NavigationDataPoint{
+			Timestamp:         start.Add(time.Duration(len(points)) * time.Second),
+			CourseOverGround:  datasource.NormalizeDirection(cog),
+			TrueWindAngle:     twa,
+			TrueWindDirection: 0,
+			TrueWindSpeed:     10,
+			SpeedOverGround:   speed,
+			SpeedThroughWater: speed,
+		})
+	}
+
+	for range 60 {
+		add(-45, 6)
+	}
+	for i := range turnSeconds {
+		add(-45+90*float64(i+1)/float64(turnSeconds+1), 6-(6-minSpeed)*float64(i+1)/float64(turnSeconds))
+	}
+	for i := range recoverySeconds {
+		add(45, minSpeed+(6-minSpeed)*float64(i+1)/float64(recoverySeconds))
+	}
+	for range 60 {
+		add(45, 6)
+	}
+
+	// The event is where TWA turns to port
+	for i, p := range points {
+		if p.TrueWindAngle < 0 {
+			return points, i
+		}
+	}
+	return points, 0
+}
+
+func TestManeuverLosses(t *testing.T) {
+	require := require.New(t)
+
+	points, index := tackPoints(6, 20, 3)
+	events := []Event{{Type: EventTack, Index: index, Timestamp: points[index].Timestamp}}
+
+	losses := ManeuverLosses(points, events, nil, DefaultLossConfig())
+	require.Len(losses, 1)
+
+	loss := losses[0]
+	require.Equal(EventTack, loss.Event.Type)
+	require.InDelta(6, loss.EntrySpeed, 0.001)
+	require.InDelta(3, loss.MinSpeed, 0.001)
+	require.InDelta(3, loss.ExitSpeed, 0.5)
+	require.InDelta(6, loss.TurnDuration().Seconds(), 2)
+	require.True(loss.Recovered)
+	require.InDelta(19, loss.RecoveryTime.Seconds(), 2)
+
+	// Turning through head to wind keeps the VMG, the loss comes from the slow recovery where
+	// the average speed is 1.5 knots below the entry speed for 20 seconds
+	require.InDelta(1.5*0.707*20/3600*datasource.MetersPerNauticalMile, loss.MetersLost, 2)
+}
+
+func TestManeuverLossesWithoutLoss(t *testing.T) {
+	require := require.New(t)
+
+	points, index := tackPoints(0, 0, 6)
+	events := []Event{
+		{Type: EventStart, Index: 0, Timestamp: points[0].Timestamp},
+		{Type: EventTack, Index: index, Timestamp: points[index].Timestamp},
+	}
+
+	losses := ManeuverLosses(points, events, nil, DefaultLossConfig())
+	require.Len(losses, 1)
+	require.InDelta(0, losses[0].MetersLost, 0.5)
+	require.Equal(time.Duration(0), losses[0].RecoveryTime)
+}
@@ -0,0 +1,265 @@
+package analysis
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+// LegType is the point of sail of a leg of the session
+type LegType int
+
+const (
+	LegPreStart LegType = iota
+	LegUpwind
+	LegDownwind
+	LegReaching
+)
+
+var legTypeNames = []string{"pre-start", "upwind", "downwind", "reaching"}
+
+func (t LegType) String() string {
+	return legTypeNames[t]
+}
+
+// Leg is a part of the session sailed on the same point of sail. The indexes refer to the
+// analysed points, the end index is inclusive.
+type Leg struct {
+	Type       LegType
+	StartIndex int
+	EndIndex   int
+	Start      time.Time
+	End        time.Time
+}
+
+// EventType is the type of a detected event
+type EventType int
+
+const (
+	EventStart EventType = iota
+	EventTack
+	EventGybe
+	EventMarkRounding
+)
+
+var eventTypeNames = []string{"start", "tack", "gybe", "mark rounding"}
+
+func (t EventType) String() string {
+	return eventTypeNames[t]
+}
+
+// Event is something that happened during the session, at the index of the analysed points
+type Event struct {
+	Type      EventType
+	Index     int
+	Timestamp time.Time
+}
+
+// IsManeuver returns true for tacks and gybes
+func (e Event) IsManeuver() bool {
+	return e.Type == EventTack || e.Type == EventGybe
+}
+
+// DetectionConfig are the thresholds for detecting the legs and events
+type DetectionConfig struct {
+	// StartTime is the start signal, if it is zero the start is detected as the beginning of
+	// the first leg that lasts at least MinLegDuration
+	StartTime time.Time
+	// MinLegDuration is the shortest time on a point of sail that is counted as a leg
+	MinLegDuration time.Duration
+	// MinTackDuration is how long a tack has to be held for the turn to be counted
+	MinTackDuration time.Duration
+	// UpwindAngle and DownwindAngle are the TWA limits of the upwind and downwind legs,
+	// the angles between them are reaching
+	UpwindAngle   float64
+	DownwindAngle float64
+}
+
+func DefaultDetectionConfig() DetectionConfig {
+	return DetectionConfig{
+		MinLegDuration:  2 * time.Minute,
+		MinTackDuration: 20 * time.Second,
+		UpwindAngle:     70,
+		DownwindAngle:   110,
+	}
+}
+
+// SessionAnalysis are the legs and events of a session, the events are ordered by time
+type SessionAnalysis struct {
+	Legs   []Leg
+	Events []Event
+}
+
+// Analyze segments the session into legs and detects the start, mark roundings and the tacks and
+// gybes sailed after the start
+func Analyze(points []datasource.NavigationDataPoint, config DetectionConfig) SessionAnalysis {
+	legs := DetectLegs(points, config)
+
+	var events []Event
+	raceStart := 0
+	for i, leg := range legs {
+		switch {
+		case leg.Type == LegPreStart:
+			continue
+		case i == 0 || legs[i-1].Type == LegPreStart:
+			raceStart = leg.StartIndex
+			events = append(events, Event{Type: EventStart, Index: leg.StartIndex, Timestamp: leg.Start})
+		default:
+			events = append(events, Event{Type: EventMarkRounding, Index: leg.StartIndex, Timestamp: leg.Start})
+		}
+	}
+
+	// The maneuvers of the pre-start are not part of the race
+	for _, e := range DetectManeuvers(points, config) {
+		if e.Index > raceStart {
+			events = append(events, e)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Index < events[j].Index })
+
+	return SessionAnalysis{Legs: legs, Events: events}
+}
+
+// DetectLegs segments the session by the point of sail. Changes of the point of sail that are
+// shorter than MinLegDuration are counted as part of the surrounding leg.
+func DetectLegs(points []datasource.NavigationDataPoint, config DetectionConfig) []Leg {
+	if len(points) == 0 {
+		return nil
+	}
+
+	var runs []Leg
+	for i, p := range points {
+		legType := pointOfSail(p.TrueWindAngle, config)
+		if len(runs) > 0 && runs[len(runs)-1].Type == legType {
+			runs[len(runs)-1].EndIndex = i
+			continue
+		}
+		runs = append(runs, Leg{Type: legType, StartIndex: i, EndIndex: i})
+	}
+	for i := range runs {
+		setLegTimes(&runs[i], points)
+	}
+
+	startIndex := 0
+	if !config.StartTime.IsZero() {
+		startIndex = sort.Search(len(points), func(i int) bool {
+			return !points[i].Timestamp.Before(config.StartTime)
+		})
+	} else {
+		for _, run := range runs {
+			if run.End.Sub(run.Start) >= config.MinLegDuration {
+				startIndex = run.StartIndex
+				break
+			}
+		}
+	}
+
+	// Merge the short runs into the previous leg, or the next one at the start of the race
+	var legs []Leg
+	if startIndex > 0 {
+		legs = append(legs, Leg{Type: LegPreStart, StartIndex: 0, EndIndex: startIndex - 1})
+	}
+	var pending *Leg
+	for _, run := range runs {
+		if run.EndIndex < startIndex {
+			continue
+		}
+		run.StartIndex = max(run.StartIndex, startIndex)
+		setLegTimes(&run, points)
+
+		last := len(legs) - 1
+		short := run.End.Sub(run.Start) < config.MinLegDuration
+		switch {
+		case last >= 0 && legs[last].Type != LegPreStart && (short || legs[last].Type == run.Type):
+			legs[last].EndIndex = run.EndIndex
+		case short:
+			if pending == nil {
+				pending = &Leg{StartIndex: run.StartIndex}
+			}
+		default:
+			if pending != nil {
+				run.StartIndex = pending.StartIndex
+				pending = nil
+			}
+			legs = append(legs, run)
+		}
+	}
+	if pending != nil {
+		legs = append(legs, Leg{Type: pointOfSail(points[pending.StartIndex].TrueWindAngle, config),
+			StartIndex: pending.StartIndex, EndIndex: len(points) - 1})
+	}
+
+	for i := range legs {
+		setLegTimes(&legs[i], points)
+	}
+	return legs
+}
+
+// DetectManeuvers finds the tacks and gybes from the changes of the sign of TWA. The new tack
+// has to be held for MinTackDuration, so that the wind angle wobbling around head to wind or
+// dead downwind isn't counted as several maneuvers.
+func DetectManeuvers(points []datasource.NavigationDataPoint, config DetectionConfig) []Event {
+	type run struct {
+		starboard  bool
+		startIndex int
+		endIndex   int
+	}
+
+	var runs []run
+	for i, p := range points {
+		starboard := p.TrueWindAngle >= 0
+		if len(runs) > 0 && runs[len(runs)-1].starboard == starboard {
+			runs[len(runs)-1].endIndex = i
+			continue
+		}
+		runs = append(runs, run{starboard: starboard, startIndex: i, endIndex: i})
+	}
+
+	// Drop the runs that are too short to be a tack, joining the runs around them
+	var held []run
+	for _, r := range runs {
+		duration := points[r.endIndex].Timestamp.Sub(points[r.startIndex].Timestamp)
+		if duration < config.MinTackDuration && len(held) > 0 {
+			continue
+		}
+		if len(held) > 0 && held[len(held)-1].starboard == r.starboard {
+			held[len(held)-1].endIndex = r.endIndex
+			continue
+		}
+		held = append(held, r)
+	}
+
+	var events []Event
+	for _, r := range held[min(1, len(held)):] {
+		// The turn is where the wind angle crossed over to the new tack for the last time
+		index := r.startIndex
+
+		eventType := EventTack
+		before := math.Abs(points[max(0, index-1)].TrueWindAngle)
+		after := math.Abs(points[index].TrueWindAngle)
+		if before+after > 180 {
+			eventType = EventGybe
+		}
+		events = append(events, Event{Type: eventType, Index: index, Timestamp: points[index].Timestamp})
+	}
+
+	return events
+}
+
+func pointOfSail(twa float64, config DetectionConfig) LegType {
+	switch twa := math.Abs(twa); {
+	case twa < config.UpwindAngle:
+		return LegUpwind
+	case twa > config.DownwindAngle:
+		return LegDownwind
+	default:
+		return LegReaching
+	}
+}
+
+func setLegTimes(leg *Leg, points []datasource.NavigationDataPoint) {
+	leg.Start = points[leg.StartIndex].Timestamp
+	leg.End = points[leg.EndIndex].Timestamp
+}
@@ -0,0 +1,93 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+// segment is a stretch sailed on a steady true wind angle
+type segment struct {
+	twa      float64
+	duration time.Duration
+}
+
+// trackPoints sails the segments at 6 knots in a northerly wind, a point per second starting from
+// the location
+func trackPoints(lat, lng float64, segments ...segment) []datasource.NavigationDataPoint {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	var points []datasource.NavigationDataPoint
+	for _, s := range segments {
+		cog := datasource.NormalizeDirection(-s.twa)
+		for range int(s.duration / time.Second) {
+			points = append(points, datasource.NavigationDataPoint{
+				Timestamp:        start.Add(time.Duration(len(points)) * time.Second),
+				Latitude:         lat,
+				Longitude:        lng,
+				CourseOverGround: cog,
+				SpeedOverGround:  6,
+				TrueWindAngle:    datasource.NormalizeAngle(s.twa),
+			})
+			distance := 6.0 / 3600 / 60
+			lat += distance * math.Cos(toRadians(cog))
+			lng += distance * math.Sin(toRadians(cog)) / math.Cos(toRadians(lat))
+		}
+	}
+	return points
+}
+
+func TestAnalyze(t *testing.T) {
+	require := require.New(t)
+
+	points := trackPoints(59.0, 24.0,
+		// Pre-start, short bits of reaching and luffing
+		segment{90, 30 * time.Second}, segment{40, 20 * time.Second}, segment{-100, 40 * time.Second},
+		// Upwind with a tack and a wobble across head to wind that is not a tack
+		segment{45, 3 * time.Minute}, segment{-44, 5 * time.Second}, segment{43, 2 * time.Minute}, segment{-45, 3 * time.Minute},
+		// Downwind with a gybe
+		segment{-150, 3 * time.Minute}, segment{150, 3 * time.Minute},
+	)
+
+	session := Analyze(points, DefaultDetectionConfig())
+
+	legTypes := make([]LegType, len(session.Legs))
+	for i, leg := range session.Legs {
+		legTypes[i] = leg.Type
+	}
+	require.Equal([]LegType{LegPreStart, LegUpwind, LegDownwind}, legTypes)
+	require.Equal(90, session.Legs[1].StartIndex)
+	require.Equal(session.Legs[1].EndIndex+1, session.Legs[2].StartIndex)
+
+	eventTypes := make([]EventType, len(session.Events))
+	for i, e := range session.Events {
+		eventTypes[i] = e.Type
+	}
+	require.Equal([]EventType{EventStart, EventTack, EventMarkRounding, EventGybe}, eventTypes)
+
+	// The tack is counted from the last crossing over to port
+	require.Equal(90+180+5+120, session.Events[1].Index)
+	require.Equal(points[session.Events[1].Index].Timestamp, session.Events[1].Timestamp)
+	require.True(session.Events[1].IsManeuver())
+	require.Equal(90+180+5+120+180+180, session.Events[3].Index)
+}
+
+func TestAnalyzeWithStartTime(t *testing.T) {
+	require := require.New(t)
+
+	points := trackPoints(59.0, 24.0, segment{45, 5 * time.Minute}, segment{-45, 5 * time.Minute})
+
+	config := DefaultDetectionConfig()
+	config.StartTime = points[60].Timestamp
+	session := Analyze(points, config)
+
+	require.Len(session.Legs, 2)
+	require.Equal(LegPreStart, session.Legs[0].Type)
+	require.Equal(59, session.Legs[0].EndIndex)
+	require.Equal(LegUpwind, session.Legs[1].Type)
+	require.Equal(EventStart, session.Events[0].Type)
+	require.Equal(60, session.Events[0].Index)
+	require.Equal(EventTack, session.Events[1].Type)
+}
@@ -0,0 +1,139 @@
+package analysis
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+// MarkType tells which end of the course the mark is at
+type MarkType int
+
+const (
+	MarkWindward MarkType = iota
+	MarkLeeward
+)
+
+var markTypeNames = []string{"windward", "leeward"}
+
+func (t MarkType) String() string {
+	return markTypeNames[t]
+}
+
+// MarkEstimate is a mark location inferred from the roundings in the logs
+type MarkEstimate struct {
+	Type      MarkType
+	Latitude  float64
+	Longitude float64
+	// Roundings is the number of roundings that the location is averaged from
+	Roundings int
+}
+
+// MarkConfig are the parameters for inferring the marks
+type MarkConfig struct {
+	Detection DetectionConfig
+	// SearchWindow is the time around the end of a leg that is searched for the point that
+	// is furthest up or down the wind
+	SearchWindow time.Duration
+	// ClusterRadius is the distance in metres within which the roundings are of the same mark
+	ClusterRadius float64
+}
+
+func DefaultMarkConfig() MarkConfig {
+	return MarkConfig{
+		Detection:     DefaultDetectionConfig(),
+		SearchWindow:  time.Minute,
+		ClusterRadius: 150,
+	}
+}
+
+// InferMarks finds the marks from the roundings in the sessions, which can be the laps of
+// one boat or the logs of several boats. A windward mark is where an upwind leg ends and a
+// leeward mark is where a downwind leg turns to upwind. The marks are ordered by the number
+// of roundings, so the best supported mark of each type comes first.
+func InferMarks(sessions [][]datasource.NavigationDataPoint, config MarkConfig) []MarkEstimate {
+	var roundings []MarkEstimate
+	for _, points := range sessions {
+		legs := DetectLegs(points, config.Detection)
+		for i := 0; i+1 < len(legs); i++ {
+			switch {
+			case legs[i].Type == LegUpwind:
+				roundings = append(roundings, roundingPoint(points, legs[i].EndIndex, MarkWindward, config))
+			case legs[i].Type == LegDownwind && legs[i+1].Type == LegUpwind:
+				roundings = append(roundings, roundingPoint(points, legs[i].EndIndex, MarkLeeward, config))
+			}
+		}
+	}
+
+	var marks []MarkEstimate
+	for _, r := range roundings {
+		found := false
+		for i := range marks {
+			m := &marks[i]
+			distance := datasource.Distance(m.Latitude, m.Longitude, r.Latitude, r.Longitude) * datasource.MetersPerNauticalMile
+			if m.Type == r.Type && distance <= config.ClusterRadius {
+				n := float64(m.Roundings)
+				m.Latitude = (m.Latitude*n + r.Latitude) / (n + 1)
+				m.Longitude = (m.Longitude*n + r.Longitude) / (n + 1)
+				m.Roundings++
+				found = true
+				break
+			}
+		}
+		if !found {
+			marks = append(marks, r)
+		}
+	}
+
+	sort.SliceStable(marks, func(i, j int) bool { return marks[i].Roundings > marks[j].Roundings })
+	return marks
+}
+
+// InferWindwardMark returns the windward mark with the most roundings
+func InferWindwardMark(sessions [][]datasource.NavigationDataPoint, config MarkConfig) (MarkEstimate, bool) {
+	for _, m := range InferMarks(sessions, config) {
+		if m.Type == MarkWindward {
+			return m, true
+		}
+	}
+	return MarkEstimate{}, false
+}
+
+// roundingPoint returns the point around the end of the leg that is furthest upwind for the
+// windward mark and furthest downwind for the leeward mark
+func roundingPoint(points []datasource.NavigationDataPoint, index int, markType MarkType, config MarkConfig) MarkEstimate {
+	p := points[index]
+	twd := toRadians(p.TrueWindDirection)
+	lngScale := math.Cos(toRadians(p.Latitude))
+
+	progress := func(q datasource.NavigationDataPoint) float64 {
+		north := q.Latitude - p.Latitude
+		east := (q.Longitude - p.Longitude) * lngScale
+		upwind := north*math.Cos(twd) + east*math.Sin(twd)
+		if markType == MarkLeeward {
+			return -upwind
+		}
+		return upwind
+	}
+
+	best := index
+	for i := index; i >= 0 && points[index].Timestamp.Sub(points[i].Timestamp) <= config.SearchWindow; i-- {
+		if progress(points[i]) > progress(points[best]) {
+			best = i
+		}
+	}
+	for i := index; i < len(points) && points[i].Timestamp.Sub(points[index].Timestamp) <= config.SearchWindow; i++ {
+		if progress(points[i]) > progress(points[best]) {
+			best = i
+		}
+	}
+
+	return MarkEstimate{
+		Type:      markType,
+		Latitude:  points[best].Latitude,
+		Longitude: points[best].Longitude,
+		Roundings: 1,
+	}
+}
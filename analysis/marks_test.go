@@ -0,0 +1,40 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+func TestInferMarks(t *testing.T) {
+	require := require.New(t)
+
+	lap := []segment{{45, 3 * time.Minute}, {-45, 3 * time.Minute}, {180, 255 * time.Second}}
+	points := trackPoints(59.0, 24.0, append(lap, lap...)...)
+	// Another boat that overstood a bit
+	other := trackPoints(59.0, 24.0, segment{45, 3 * time.Minute}, segment{-45, 195 * time.Second}, segment{180, 4 * time.Minute})
+
+	marks := InferMarks([][]datasource.NavigationDataPoint{points, other}, DefaultMarkConfig())
+	require.Len(marks, 2)
+
+	// Both laps and the other boat round the windward mark
+	windwardLat := 59.0 + 6*6.0/60*math.Cos(toRadians(45))/60
+	require.Equal(MarkWindward, marks[0].Type)
+	require.Equal(3, marks[0].Roundings)
+	require.InDelta(windwardLat, marks[0].Latitude, 0.0002)
+	require.InDelta(24.0, marks[0].Longitude, 0.0005)
+
+	require.Equal(MarkLeeward, marks[1].Type)
+	require.Equal(1, marks[1].Roundings)
+	require.Less(marks[1].Latitude, windwardLat)
+
+	mark, ok := InferWindwardMark([][]datasource.NavigationDataPoint{points}, DefaultMarkConfig())
+	require.True(ok)
+	require.Equal(2, mark.Roundings)
+
+	_, ok = InferWindwardMark([][]datasource.NavigationDataPoint{trackPoints(59.0, 24.0, segment{-45, 5 * time.Minute})}, DefaultMarkConfig())
+	require.False(ok)
+}
@@ -0,0 +1,93 @@
+package analysis
+
+import (
+	"math"
+	"time"
+
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+// Performance are the numbers computed from a navigation data point that are looked at when
+// debriefing a race
+type Performance struct {
+	// VMG is the velocity made good to wind in knots, negative when sailing downwind
+	VMG float64
+	// VMC is the velocity made good towards the mark in knots
+	VMC float64
+	// TargetSpeed is the polar boat speed for the current TWA and TWS, 0 without a polar
+	TargetSpeed float64
+	// PolarPercentage is the speed through water as a percentage of the target speed
+	PolarPercentage float64
+	// BearingToMark is the true bearing in degrees and DistanceToMark is in nautical miles
+	BearingToMark  float64
+	DistanceToMark float64
+	// StarboardLayline and PortLayline are the distances in nautical miles to sail on that tack
+	// before the mark can be laid on the other tack. They are negative when the layline has
+	// already been overstood.
+	StarboardLayline float64
+	PortLayline      float64
+	// StarboardLaylineTime and PortLaylineTime are the times to reach the laylines
+	StarboardLaylineTime time.Duration
+	PortLaylineTime      time.Duration
+}
+
+// HasPolar returns true if the target speeds are known
+func (perf Performance) HasPolar() bool {
+	return perf.TargetSpeed > 0
+}
+
+// ComputePerformance computes the performance numbers for the data point. The polar can be nil,
+// then there are no targets and the laylines use the default beat and run angles.
+func ComputePerformance(p datasource.NavigationDataPoint, markLat, markLng float64, polar *Polar) Performance {
+	perf := Performance{
+		VMG:            vmg(p),
+		BearingToMark:  datasource.Bearing(p.Latitude, p.Longitude, markLat, markLng),
+		DistanceToMark: datasource.Distance(p.Latitude, p.Longitude, markLat, markLng),
+	}
+	perf.VMC = p.SpeedOverGround * math.Cos(toRadians(p.CourseOverGround-perf.BearingToMark))
+
+	beat, run := DefaultBeatAngle, DefaultRunAngle
+	if polar != nil {
+		perf.TargetSpeed = polar.TargetSpeed(p.TrueWindAngle, p.TrueWindSpeed)
+		if perf.TargetSpeed > 0 {
+			perf.PolarPercentage = 100 * p.SpeedThroughWater / perf.TargetSpeed
+		}
+		beat, run = polar.OptimalAngles(p.TrueWindSpeed)
+	}
+
+	// Split the way to the mark into legs on both tacks in the wind aligned frame, where the
+	// starboard tack heads to the left of the wind and the port tack to the right of it
+	markAngle := toRadians(perf.BearingToMark - p.TrueWindDirection)
+	upwind := perf.DistanceToMark * math.Cos(markAngle)
+	across := perf.DistanceToMark * math.Sin(markAngle)
+
+	layAngle := beat
+	if upwind < 0 {
+		layAngle = run
+	}
+	sinLay := math.Sin(toRadians(layAngle))
+	cosLay := math.Cos(toRadians(layAngle))
+	perf.StarboardLayline = (upwind/cosLay - across/sinLay) / 2
+	perf.PortLayline = (upwind/cosLay + across/sinLay) / 2
+
+	speed := p.SpeedOverGround
+	if polar != nil {
+		speed = polar.TargetSpeed(layAngle, p.TrueWindSpeed)
+	}
+	perf.StarboardLaylineTime = timeToSail(perf.StarboardLayline, speed)
+	perf.PortLaylineTime = timeToSail(perf.PortLayline, speed)
+
+	return perf
+}
+
+// timeToSail returns the time to sail the distance in nautical miles at the speed in knots
+func timeToSail(distance, speed float64) time.Duration {
+	if speed <= 0 || distance <= 0 {
+		return 0
+	}
+	return time.Duration(distance / speed * float64(time.Hour))
+}
+
+func toRadians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
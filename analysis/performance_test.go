@@ -0,0 +1,69 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+func TestComputePerformance(t *testing.T) {
+	require := require.New(t)
+
+	// Mark is one mile straight upwind, wind from the north, sailing on starboard tack
+	p := datasource.NavigationDataPoint{
+		Latitude:          59.0,
+		Longitude:         24.0,
+		TrueWindDirection: 0,
+		TrueWindAngle:     45,
+		TrueWindSpeed:     10,
+		CourseOverGround:  315,
+		SpeedOverGround:   6,
+		SpeedThroughWater: 6,
+	}
+	markLat, markLng := 59.0+1.0/60, 24.0
+
+	perf := ComputePerformance(p, markLat, markLng, nil)
+	require.InDelta(4.243, perf.VMG, 0.001)
+	require.InDelta(4.243, perf.VMC, 0.001)
+	require.InDelta(0, perf.BearingToMark, 0.001)
+	require.InDelta(1, perf.DistanceToMark, 0.001)
+	require.False(perf.HasPolar())
+
+	// Both laylines are half way, sqrt(2)/2 miles on either tack
+	require.InDelta(0.707, perf.StarboardLayline, 0.001)
+	require.InDelta(0.707, perf.PortLayline, 0.001)
+	require.InDelta((7*time.Minute + 4*time.Second).Seconds(), perf.StarboardLaylineTime.Seconds(), 1)
+
+	// Mark is to the right of the wind, the starboard tack reaches the layline sooner
+	perf = ComputePerformance(p, markLat, markLng+0.01, nil)
+	require.Less(perf.StarboardLayline, perf.PortLayline)
+
+	polar, err := ReadPolar(strings.NewReader(testPolar))
+	require.NoError(err)
+	perf = ComputePerformance(p, markLat, markLng, polar)
+	require.True(perf.HasPolar())
+	require.InDelta(100*6/polar.TargetSpeed(45, 10), perf.PolarPercentage, 0.001)
+}
+
+func TestComputePerformanceDownwind(t *testing.T) {
+	require := require.New(t)
+
+	// Mark is one mile straight downwind
+	p := datasource.NavigationDataPoint{
+		Latitude:          59.0,
+		Longitude:         24.0,
+		TrueWindDirection: 0,
+		TrueWindAngle:     150,
+		CourseOverGround:  210,
+		SpeedOverGround:   7,
+	}
+
+	perf := ComputePerformance(p, 59.0-1.0/60, 24.0, nil)
+	require.Less(perf.VMG, 0.0)
+	require.InDelta(180, perf.BearingToMark, 0.001)
+	require.InDelta(0.577, perf.StarboardLayline, 0.001)
+	require.InDelta(0.577, perf.PortLayline, 0.001)
+}
@@ -0,0 +1,147 @@
+package analysis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultBeatAngle is the upwind TWA that is used for the laylines without a polar
+	DefaultBeatAngle = 45.0
+	// DefaultRunAngle is the downwind TWA that is used for the laylines without a polar
+	DefaultRunAngle = 150.0
+)
+
+// Polar is the target boat speed table by true wind angle and true wind speed
+type Polar struct {
+	windSpeeds []float64
+	windAngles []float64
+	// speeds are indexed by the wind angle and then the wind speed
+	speeds [][]float64
+}
+
+// ReadPolar reads a polar table in the common .pol format. The first line has the true wind
+// speeds, the following lines start with the true wind angle followed by the boat speeds for
+// each wind speed. The values can be separated by tabs, semicolons, commas or spaces.
+func ReadPolar(reader io.Reader) (*Polar, error) {
+	var rows [][]float64
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.FieldsFunc(scanner.Text(), func(r rune) bool {
+			return r == '\t' || r == ';' || r == ',' || r == ' '
+		})
+		if len(fields) == 0 {
+			continue
+		}
+
+		// The header starts with a label such as "twa/tws"
+		if len(rows) == 0 {
+			if _, err := strconv.ParseFloat(fields[0], 64); err != nil {
+				fields = fields[1:]
+			}
+		}
+
+		row := make([]float64, len(fields))
+		for i, field := range fields {
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			row[i] = v
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(rows) < 2 || len(rows[0]) == 0 {
+		return nil, errors.New("no polar data found")
+	}
+
+	polar := &Polar{windSpeeds: rows[0]}
+	sort.SliceStable(rows[1:], func(i, j int) bool { return rows[i+1][0] < rows[j+1][0] })
+	for _, row := range rows[1:] {
+		if len(row) != len(polar.windSpeeds)+1 {
+			return nil, fmt.Errorf("polar row for TWA %v has %d speeds, expected %d",
+				row[0], len(row)-1, len(polar.windSpeeds))
+		}
+		polar.windAngles = append(polar.windAngles, row[0])
+		polar.speeds = append(polar.speeds, row[1:])
+	}
+
+	return polar, nil
+}
+
+// TargetSpeed returns the boat speed for the true wind angle and speed, interpolated
+// between the table values. The tack doesn't matter, so the angle can be signed.
+func (p *Polar) TargetSpeed(twa, tws float64) float64 {
+	twa = math.Abs(twa)
+
+	i, fa := interpolationIndex(p.windAngles, twa)
+	j, fs := interpolationIndex(p.windSpeeds, tws)
+
+	speedAt := func(i int) float64 {
+		row := p.speeds[i]
+		if j+1 >= len(row) {
+			return row[j]
+		}
+		return row[j] + (row[j+1]-row[j])*fs
+	}
+
+	speed := speedAt(i)
+	if i+1 < len(p.speeds) {
+		speed += (speedAt(i+1) - speed) * fa
+	}
+
+	// Below the first angle of the table the boat is pointing too high to sail
+	if twa < p.windAngles[0] {
+		speed *= twa / p.windAngles[0]
+	}
+
+	return speed
+}
+
+// OptimalAngles returns the true wind angles that give the best VMG upwind and downwind
+// at the wind speed
+func (p *Polar) OptimalAngles(tws float64) (beat, run float64) {
+	var bestUp, bestDown float64
+	for twa := 1.0; twa <= 180; twa++ {
+		vmg := p.TargetSpeed(twa, tws) * math.Cos(twa*math.Pi/180)
+		if vmg > bestUp {
+			bestUp, beat = vmg, twa
+		}
+		if -vmg > bestDown {
+			bestDown, run = -vmg, twa
+		}
+	}
+
+	if beat == 0 {
+		beat = DefaultBeatAngle
+	}
+	if run == 0 {
+		run = DefaultRunAngle
+	}
+	return beat, run
+}
+
+// interpolationIndex returns the index of the last value at or below v and the fraction of the
+// way to the next value. Values outside of the table are clamped to the first or last value.
+func interpolationIndex(values []float64, v float64) (int, float64) {
+	if v <= values[0] {
+		return 0, 0
+	}
+
+	i := sort.SearchFloat64s(values, v) - 1
+	if i >= len(values)-1 {
+		return len(values) - 1, 0
+	}
+
+	return i, (v - values[i]) / (values[i+1] - values[i])
+}
@@ -0,0 +1,56 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPolar = `twa/tws;6;10;14
+40;4.5;5.5;6.0
+52;5.2;6.4;6.9
+90;5.8;7.0;7.4
+150;4.6;6.3;7.3
+180;4.0;5.6;6.8
+`
+
+func TestReadPolar(t *testing.T) {
+	require := require.New(t)
+
+	polar, err := ReadPolar(strings.NewReader(testPolar))
+	require.NoError(err)
+
+	// Table values
+	require.InDelta(6.4, polar.TargetSpeed(52, 10), 0.001)
+	require.InDelta(6.4, polar.TargetSpeed(-52, 10), 0.001)
+
+	// Interpolated by wind speed and angle
+	require.InDelta(5.8, polar.TargetSpeed(52, 8), 0.001)
+	require.InDelta(6.7, polar.TargetSpeed(71, 10), 0.001)
+
+	// Clamped to the table
+	require.InDelta(7.4, polar.TargetSpeed(90, 25), 0.001)
+	require.InDelta(2.75, polar.TargetSpeed(20, 10), 0.001)
+}
+
+func TestReadPolarErrors(t *testing.T) {
+	require := require.New(t)
+
+	_, err := ReadPolar(strings.NewReader("twa/tws;6;10\n"))
+	require.Error(err)
+
+	_, err = ReadPolar(strings.NewReader("twa/tws;6;10\n40;4.5\n"))
+	require.Error(err)
+}
+
+func TestOptimalAngles(t *testing.T) {
+	require := require.New(t)
+
+	polar, err := ReadPolar(strings.NewReader(testPolar))
+	require.NoError(err)
+
+	beat, run := polar.OptimalAngles(10)
+	require.InDelta(45, beat, 8)
+	require.InDelta(160, run, 20)
+}
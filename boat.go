@@ -1,12 +1,18 @@
 package gosailing
 
 import (
+	"image/color"
 	"math"
 
 	"github.com/gopxl/pixel/v2/ext/imdraw"
+	"github.io/mpihlak/gosailing/datasource"
 	"golang.org/x/image/colornames"
 )
 
+// LuffingSpeed is the speed of a free boat that is pointing closer to the wind than it can sail,
+// relative to the full speed of one pixel per tick
+const LuffingSpeed = 0.25
+
 type Boat struct {
 	currentX       float64
 	currentY       float64
@@ -14,7 +20,11 @@ type Boat struct {
 	windDirection  float64
 	sailedDistance float64
 	laylines       bool
-	boat           *imdraw.IMDraw
+	downwind       bool
+	// free boats can be steered to any heading, otherwise the boat sails close-hauled or downwind
+	free  bool
+	color color.RGBA
+	boat  *imdraw.IMDraw
 }
 
 func NewBoat(currentX, currentY, windDirection float64) *Boat {
@@ -27,6 +37,7 @@ func NewBoat(currentX, currentY, windDirection float64) *Boat {
 		heading:       heading,
 		windDirection: windDirection,
 		laylines:      true,
+		color:         colornames.Darkblue,
 		boat:          imdraw.New(nil),
 	}
 }
@@ -39,31 +50,92 @@ func (b *Boat) Reset() {
 	b.windDirection = 0
 }
 
+// ResetLocation places the boat at the location and resets the sailed distance
+func (b *Boat) ResetLocation(x, y, heading, windDirection float64) {
+	b.Reset()
+	b.currentX = x
+	b.currentY = y
+	b.heading = heading
+	b.windDirection = windDirection
+}
+
 func (b *Boat) SetWindDirection(direction float64) {
 	b.windDirection = direction
+	if b.free {
+		return
+	}
 
-	// Drive as close to the wind as possible
+	// Drive as close to the wind as possible, or as deep as possible downwind
 	if b.heading < b.windDirection {
-		b.heading = b.windDirection - TackAngle
+		b.heading = b.windDirection - b.sailingAngle()
 	} else {
-		b.heading = b.windDirection + TackAngle
+		b.heading = b.windDirection + b.sailingAngle()
+	}
+}
+
+// SetDownwind sets the boat sailing downwind or upwind on the same tack
+func (b *Boat) SetDownwind(downwind bool) {
+	b.downwind = downwind
+	b.SetWindDirection(b.windDirection)
+}
+
+// SetFree lets the boat be steered to any heading, when it is no longer free it sails close-hauled
+// or downwind on the tack it is on
+func (b *Boat) SetFree(free bool) {
+	b.free = free
+	if !free {
+		b.heading = b.windDirection + datasource.NormalizeAngle(b.heading-b.windDirection)
+		b.SetWindDirection(b.windDirection)
 	}
 }
 
+// Steer turns a free boat by the degrees, positive to the right
+func (b *Boat) Steer(degrees float64) {
+	if b.free {
+		b.heading += degrees
+	}
+}
+
+// Tack tacks the boat upwind and gybes it downwind, a free boat turns to the same angle to the
+// wind on the other tack
 func (b *Boat) Tack() {
+	if b.free {
+		b.heading = b.windDirection - datasource.NormalizeAngle(b.heading-b.windDirection)
+		return
+	}
 	if b.heading < b.windDirection {
-		b.heading = b.windDirection + TackAngle
+		b.heading = b.windDirection + b.sailingAngle()
 	} else {
-		b.heading = b.windDirection - TackAngle
+		b.heading = b.windDirection - b.sailingAngle()
+	}
+}
+
+// sailingAngle returns the angle between the heading and the wind direction
+func (b *Boat) sailingAngle() float64 {
+	if b.downwind {
+		return 180 - GybeAngle
 	}
+	return TackAngle
 }
 
 func (b *Boat) ToggleLaylines() {
 	b.laylines = !b.laylines
 }
 
+func (b *Boat) SetLaylines(laylines bool) {
+	b.laylines = laylines
+}
+
+func (b *Boat) SetColor(color color.RGBA) {
+	b.color = color
+}
+
 func (b *Boat) Advance() {
-	newX, newY := RotatePoint(b.currentX, b.currentY+1, b.currentX, b.currentY, b.heading)
+	speed := 1.0
+	if b.free && math.Abs(datasource.NormalizeAngle(b.heading-b.windDirection)) < TackAngle {
+		speed = LuffingSpeed
+	}
+	newX, newY := RotatePoint(b.currentX, b.currentY+speed, b.currentX, b.currentY, b.heading)
 	b.sailedDistance += math.Hypot(b.currentX-newX, b.currentY-newY)
 	b.currentX = newX
 	b.currentY = newY
@@ -88,11 +160,11 @@ func (b *Boat) GetSailedDistance() float64 {
 func (b *Boat) Drawable() *imdraw.IMDraw {
 	b.boat.Clear()
 
-	DrawBoat(b.boat, b.currentX, b.currentY, b.heading)
+	DrawBoat(b.boat, b.currentX, b.currentY, b.heading, b.color)
 
 	if b.laylines {
-		LayLine(b.boat, b.currentX, b.currentY, b.windDirection+TackAngle+180, colornames.Red)
-		LayLine(b.boat, b.currentX, b.currentY, b.windDirection-TackAngle+180, colornames.Green)
+		LayLine(b.boat, b.currentX, b.currentY, b.windDirection+b.sailingAngle()+180, colornames.Red)
+		LayLine(b.boat, b.currentX, b.currentY, b.windDirection-b.sailingAngle()+180, colornames.Green)
 		LayLine(b.boat, b.currentX, b.currentY, b.heading+180, colornames.Gray)
 	}
 
@@ -0,0 +1,171 @@
+package gosailing
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gopxl/pixel/v2"
+	"github.com/gopxl/pixel/v2/backends/opengl"
+	"github.com/gopxl/pixel/v2/ext/imdraw"
+	"github.com/gopxl/pixel/v2/ext/text"
+	"github.io/mpihlak/gosailing/datasource"
+	"golang.org/x/image/colornames"
+)
+
+// bookmarkNoteDuration is how long the note of a bookmark is shown after its time
+const bookmarkNoteDuration = 10 * time.Second
+
+// SetBookmarks sets the bookmarks of our own boat's log. The callback is called with all the
+// bookmarks whenever they are changed, so that they can be saved.
+func (rr *RaceReplay) SetBookmarks(bookmarks datasource.Bookmarks, onChange func(datasource.Bookmarks)) {
+	rr.bookmarks = bookmarks
+	rr.onBookmarksChange = onChange
+	rr.updateBookmarkMarkers()
+}
+
+// Bookmarks returns the bookmarks of our own boat's log
+func (rr *RaceReplay) Bookmarks() datasource.Bookmarks {
+	return rr.bookmarks
+}
+
+func (rr *RaceReplay) bookmarksChanged() {
+	rr.updateBookmarkMarkers()
+	if rr.onBookmarksChange != nil {
+		rr.onBookmarksChange(rr.bookmarks)
+	}
+}
+
+func (rr *RaceReplay) updateBookmarkMarkers() {
+	times := make([]time.Time, len(rr.bookmarks))
+	for i, b := range rr.bookmarks {
+		times[i] = b.Time
+	}
+	rr.timeline.SetBookmarks(times)
+}
+
+// StartNote pauses the replay and starts typing the note of a new bookmark at the current time
+func (rr *RaceReplay) StartNote() {
+	if !rr.started {
+		return
+	}
+	rr.paused = true
+	rr.editingNote = true
+	rr.note = ""
+}
+
+// EditingNote returns true while a note is being typed, the keys are then for the note only
+func (rr *RaceReplay) EditingNote() bool {
+	return rr.editingNote
+}
+
+// HandleTyping adds the typed text to the note. Enter saves the bookmark and Escape cancels it.
+func (rr *RaceReplay) HandleTyping(win *opengl.Window) {
+	rr.note += win.Typed()
+	if (win.JustPressed(pixel.KeyBackspace) || win.Repeated(pixel.KeyBackspace)) && len(rr.note) > 0 {
+		runes := []rune(rr.note)
+		rr.note = string(runes[:len(runes)-1])
+	}
+	if win.JustPressed(pixel.KeyEscape) {
+		rr.editingNote = false
+	}
+	if win.JustPressed(pixel.KeyEnter) || win.JustPressed(pixel.KeyKPEnter) {
+		rr.editingNote = false
+		rr.bookmarks.Add(rr.playTime, rr.note)
+		rr.bookmarksChanged()
+	}
+}
+
+// ToggleBookmarks shows or hides the list of the bookmarks, in place of the channels and events
+func (rr *RaceReplay) ToggleBookmarks() {
+	rr.bookmarkList = !rr.bookmarkList
+	rr.channels = false
+	rr.events = false
+}
+
+// JumpToBookmark seeks the replay to the bookmark, a few seconds before it
+func (rr *RaceReplay) JumpToBookmark(i int) {
+	if i < 0 || i >= len(rr.bookmarks) {
+		return
+	}
+	rr.SeekTime(rr.bookmarks[i].Time.Add(-eventLeadTime))
+}
+
+// NextBookmark jumps to the first bookmark after the current one
+func (rr *RaceReplay) NextBookmark() {
+	rr.JumpToBookmark(rr.currentBookmark() + 1)
+}
+
+// PreviousBookmark jumps to the bookmark before the current one
+func (rr *RaceReplay) PreviousBookmark() {
+	rr.JumpToBookmark(rr.currentBookmark() - 1)
+}
+
+// RemoveBookmark removes the current bookmark
+func (rr *RaceReplay) RemoveBookmark() {
+	if i := rr.currentBookmark(); i >= 0 {
+		rr.bookmarks.Remove(i)
+		rr.bookmarksChanged()
+	}
+}
+
+// currentBookmark returns the index of the last bookmark that the replay has reached, counting
+// the bookmark that was jumped to as reached. Returns -1 before the first bookmark.
+func (rr *RaceReplay) currentBookmark() int {
+	reached := rr.playTime.Add(eventLeadTime)
+	return sort.Search(len(rr.bookmarks), func(i int) bool {
+		return rr.bookmarks[i].Time.After(reached)
+	}) - 1
+}
+
+func (rr *RaceReplay) drawBookmarks(win *opengl.Window, atlas *text.Atlas) {
+	bounds := win.Bounds()
+	listTxt := text.New(pixel.V(bounds.W()-250, bounds.H()-25), atlas)
+	listTxt.Color = colornames.Black
+
+	if len(rr.bookmarks) == 0 {
+		fmt.Fprintln(listTxt, "No bookmarks")
+	}
+
+	current := rr.currentBookmark()
+	first := max(0, min(current-maxEventLines/2, len(rr.bookmarks)-maxEventLines))
+	for i := first; i < len(rr.bookmarks) && i < first+maxEventLines; i++ {
+		listTxt.Color = colornames.Black
+		if i == current {
+			listTxt.Color = colornames.Darkgoldenrod
+		}
+		fmt.Fprintf(listTxt, "%s %s\n", rr.bookmarks[i].Time.Format("15:04:05"), rr.bookmarks[i].Note)
+	}
+	listTxt.Draw(win, pixel.IM.Scaled(listTxt.Orig, 1.5))
+}
+
+// drawNote shows the note being typed, or the note of the bookmark that was just passed
+func (rr *RaceReplay) drawNote(win *opengl.Window, atlas *text.Atlas) {
+	var note string
+	switch i := rr.currentBookmark(); {
+	case rr.editingNote:
+		note = fmt.Sprintf("Note at %s: %s_", rr.playTime.Format("15:04:05"), rr.note)
+	case i >= 0 && rr.playTime.Sub(rr.bookmarks[i].Time) < bookmarkNoteDuration:
+		note = rr.bookmarks[i].Note
+	default:
+		return
+	}
+
+	bounds := win.Bounds()
+	noteTxt := text.New(pixel.V(bounds.Center().X, bounds.H()-60), atlas)
+	noteTxt.Color = colornames.Black
+	noteTxt.Dot.X -= noteTxt.BoundsOf(note).W() / 2
+	fmt.Fprint(noteTxt, note)
+
+	scale := 2.0
+	textBounds := noteTxt.Bounds()
+	background := imdraw.New(nil)
+	background.Color = colornames.Lightyellow
+	bottomLeft := noteTxt.Orig.Add(textBounds.Min.Sub(noteTxt.Orig).Scaled(scale)).Sub(pixel.V(8, 6))
+	topRight := noteTxt.Orig.Add(textBounds.Max.Sub(noteTxt.Orig).Scaled(scale)).Add(pixel.V(8, 6))
+	background.Push(bottomLeft, topRight)
+	background.Rectangle(0)
+	background.Draw(win)
+
+	noteTxt.Draw(win, pixel.IM.Scaled(noteTxt.Orig, scale))
+}
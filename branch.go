@@ -0,0 +1,133 @@
+package gosailing
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gopxl/pixel/v2"
+	"github.io/mpihlak/gosailing/analysis"
+	"github.io/mpihlak/gosailing/datasource"
+	"golang.org/x/image/colornames"
+)
+
+// replayBranch is a simulated boat that is branched from our own boat in a paused replay, to
+// see what would have happened when tacking differently from then on
+type replayBranch struct {
+	sim   *analysis.Counterfactual
+	boat  *Boat
+	track *TrackPlotter
+	// points are the locations on the plane that the simulated boat has sailed through
+	points []pixel.Vec
+	// plotted is the screen transform that the track was plotted with
+	plotted ScreenTransform
+}
+
+// Branch starts a simulated boat from the current location of our own boat when the replay is
+// paused, or removes the simulated boat if there already is one
+func (rr *RaceReplay) Branch() {
+	if rr.branch != nil {
+		rr.branch = nil
+		return
+	}
+	if !rr.paused {
+		return
+	}
+
+	own := rr.boats[0]
+	points := make([]datasource.NavigationDataPoint, len(own.data))
+	for i, p := range own.data {
+		points[i] = p.NavigationDataPoint
+	}
+
+	sim := analysis.NewCounterfactual(points, own.currentPos, rr.markLat, rr.markLng, rr.polar, analysis.DefaultCounterfactualConfig())
+	boat := NewBoat(0, 0, 0)
+	boat.SetLaylines(false)
+	boat.SetColor(Translucent(colornames.Darkgreen, 0.6))
+	track := NewTrackPlotter(0, 0)
+	track.SetColor(Translucent(colornames.Darkgreen, 0.6))
+
+	rr.branch = &replayBranch{sim: sim, boat: boat, track: track}
+	rr.branch.points = append(rr.branch.points, rr.branchLocation())
+	rr.layoutBranch()
+	rr.updateBranch()
+}
+
+// TackBranch tacks or gybes the simulated boat
+func (rr *RaceReplay) TackBranch() {
+	if rr.branch != nil {
+		rr.branch.sim.Tack()
+	}
+}
+
+// ToggleAutoTack makes the simulated boat tack on every header
+func (rr *RaceReplay) ToggleAutoTack() {
+	if rr.branch != nil {
+		rr.branch.sim.AutoTack = !rr.branch.sim.AutoTack
+	}
+}
+
+// branchLocation returns the location of the simulated boat on the plane
+func (rr *RaceReplay) branchLocation() pixel.Vec {
+	lat, lng := rr.branch.sim.Location()
+	return rr.latLngToPlane(datasource.LatLng{Latitude: lat, Longitude: lng})
+}
+
+// updateBranch sails the simulated boat until the playback time
+func (rr *RaceReplay) updateBranch() {
+	b := rr.branch
+	simTime := b.sim.Time
+	b.sim.AdvanceTo(rr.playTime)
+	location := rr.branchLocation()
+
+	x, y := rr.screen.ToScreen(location.X, location.Y)
+	b.boat.SetLocation(x, y, b.sim.Heading-rr.medianWind, rr.boats[0].current().twd)
+
+	// The track only grows when the simulated boat has sailed, not while paused
+	if b.sim.Time.After(simTime) {
+		b.points = append(b.points, location)
+		b.track.PlotLocation(b.plotted.ToScreen(location.X, location.Y))
+	}
+}
+
+// layoutBranch places the simulated boat after the camera has moved, the track is plotted again
+// when the camera has zoomed
+func (rr *RaceReplay) layoutBranch() {
+	b := rr.branch
+	if b.plotted.MetersPerPixel != rr.screen.MetersPerPixel {
+		b.plotted = *rr.screen
+		b.track.Restart(b.plotted.ToScreen(b.points[0].X, b.points[0].Y))
+		for _, p := range b.points[1:] {
+			b.track.PlotLocation(b.plotted.ToScreen(p.X, p.Y))
+		}
+	}
+	last := b.points[len(b.points)-1]
+	x, y := rr.screen.ToScreen(last.X, last.Y)
+	b.boat.ResetLocation(x, y, b.sim.Heading-rr.medianWind, rr.boats[0].current().twd)
+}
+
+// printBranch compares the simulated boat to the log at the mark
+func (rr *RaceReplay) printBranch(w io.Writer) {
+	sim := rr.branch.sim
+	mode := "manual"
+	if sim.AutoTack {
+		mode = "auto"
+	}
+	fmt.Fprintf(w, "What if: %s tacking, tacks %d\n", mode, sim.Tacks)
+
+	if !sim.Arrived {
+		fmt.Fprintf(w, "What if to mark: %s\n", formatDistance(sim.DistanceToMark()))
+		return
+	}
+	if !sim.ActualArrived {
+		fmt.Fprintf(w, "What if at mark: %s, the log never got there\n", sim.ArrivalTime.Format("15:04:05"))
+		return
+	}
+
+	gain := sim.ActualArrivalTime.Sub(sim.ArrivalTime).Truncate(time.Second)
+	comparison := fmt.Sprintf("%s ahead of", gain)
+	if gain < 0 {
+		comparison = fmt.Sprintf("%s behind", -gain)
+	}
+	fmt.Fprintf(w, "What if at mark: %s, %s the log\n", sim.ArrivalTime.Format("15:04:05"), comparison)
+}
@@ -0,0 +1,56 @@
+package gosailing
+
+import (
+	"math"
+
+	"github.com/gopxl/pixel/v2"
+)
+
+// Zoom limits of the camera in metres per pixel
+const (
+	minMetersPerPixel = 0.2
+	maxMetersPerPixel = 200.0
+)
+
+// Camera moves and zooms the view of the plane by changing the screen transform
+type Camera struct {
+	screen *ScreenTransform
+}
+
+func NewCamera(screen *ScreenTransform) *Camera {
+	return &Camera{screen: screen}
+}
+
+// Fit zooms and centers the camera so that the area of the plane fills the viewport on the screen
+func (c *Camera) Fit(area, viewport pixel.Rect) {
+	metersPerPixel := math.Max(area.W()/viewport.W(), area.H()/viewport.H())
+	if metersPerPixel <= 0 || math.IsNaN(metersPerPixel) {
+		metersPerPixel = c.screen.MetersPerPixel
+	}
+	c.screen.MetersPerPixel = clampScale(metersPerPixel)
+	c.CenterOn(area.Center(), viewport.Center())
+}
+
+// Zoom scales the view by the factor around the screen location, factors over one zoom in
+func (c *Camera) Zoom(at pixel.Vec, factor float64) {
+	x, y := c.screen.ToPlane(at.X, at.Y)
+	c.screen.MetersPerPixel = clampScale(c.screen.MetersPerPixel / factor)
+	c.screen.OffsetX = at.X - x/c.screen.MetersPerPixel
+	c.screen.OffsetY = at.Y - y/c.screen.MetersPerPixel
+}
+
+// Pan moves the view by the distance in pixels
+func (c *Camera) Pan(delta pixel.Vec) {
+	c.screen.OffsetX += delta.X
+	c.screen.OffsetY += delta.Y
+}
+
+// CenterOn moves the view so that the location on the plane is at the screen location
+func (c *Camera) CenterOn(location, at pixel.Vec) {
+	c.screen.OffsetX = at.X - location.X/c.screen.MetersPerPixel
+	c.screen.OffsetY = at.Y - location.Y/c.screen.MetersPerPixel
+}
+
+func clampScale(metersPerPixel float64) float64 {
+	return math.Min(maxMetersPerPixel, math.Max(minMetersPerPixel, metersPerPixel))
+}
@@ -0,0 +1,45 @@
+package gosailing
+
+import (
+	"testing"
+
+	"github.com/gopxl/pixel/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCamera(t *testing.T) {
+	require := require.New(t)
+
+	screen := &ScreenTransform{MetersPerPixel: 1}
+	camera := NewCamera(screen)
+
+	// A 2 km by 1 km area fits by its width into a 1000 by 1000 pixel viewport
+	camera.Fit(pixel.R(-1000, 0, 1000, 1000), pixel.R(0, 0, 1000, 1000))
+	require.InDelta(2.0, screen.MetersPerPixel, 0.001)
+	x, y := screen.ToScreen(0, 500)
+	require.InDelta(500.0, x, 0.001)
+	require.InDelta(500.0, y, 0.001)
+
+	// Zooming keeps the location under the mouse in place
+	at := pixel.V(200, 300)
+	px, py := screen.ToPlane(at.X, at.Y)
+	camera.Zoom(at, 2)
+	require.InDelta(1.0, screen.MetersPerPixel, 0.001)
+	x, y = screen.ToScreen(px, py)
+	require.InDelta(at.X, x, 0.001)
+	require.InDelta(at.Y, y, 0.001)
+
+	camera.Pan(pixel.V(10, -20))
+	x, y = screen.ToScreen(px, py)
+	require.InDelta(at.X+10, x, 0.001)
+	require.InDelta(at.Y-20, y, 0.001)
+
+	camera.CenterOn(pixel.V(100, 100), pixel.V(500, 400))
+	x, y = screen.ToScreen(100, 100)
+	require.InDelta(500.0, x, 0.001)
+	require.InDelta(400.0, y, 0.001)
+
+	// The zoom is limited
+	camera.Zoom(at, 1000)
+	require.Equal(minMetersPerPixel, screen.MetersPerPixel)
+}
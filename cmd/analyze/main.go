@@ -0,0 +1,134 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.io/mpihlak/gosailing/analysis"
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+var (
+	csvFile    = flag.String("csv", "", "CSV data file to analyse")
+	startTime  = flag.String("start", "", "Start time of the data to analyse (RFC3339 format)")
+	endTime    = flag.String("end", "", "End time of the data to analyse (RFC3339 format)")
+	raceStart  = flag.String("raceStart", "", "Time of the start signal (RFC3339 format), detected if empty")
+	polarFile  = flag.String("polar", "", "Boat polar table for the target speeds")
+	lossWindow = flag.Duration("lossWindow", analysis.DefaultLossConfig().LossWindow, "Time from the start of the turn that the loss is measured over")
+)
+
+func main() {
+	flag.Parse()
+
+	if *csvFile == "" {
+		log.Fatalf("Must provide -csv argument with the data file")
+	}
+
+	var start, end *time.Time
+	if *startTime != "" {
+		t, err := time.Parse(time.RFC3339, *startTime)
+		if err != nil {
+			log.Fatalf("Invalid start time format: %v", err)
+		}
+		start = &t
+	}
+	if *endTime != "" {
+		t, err := time.Parse(time.RFC3339, *endTime)
+		if err != nil {
+			log.Fatalf("Invalid end time format: %v", err)
+		}
+		end = &t
+	}
+
+	detection := analysis.DefaultDetectionConfig()
+	if *raceStart != "" {
+		t, err := time.Parse(time.RFC3339, *raceStart)
+		if err != nil {
+			log.Fatalf("Invalid race start time format: %v", err)
+		}
+		detection.StartTime = t
+	}
+
+	var polar *analysis.Polar
+	if *polarFile != "" {
+		pf, err := os.Open(*polarFile)
+		if err != nil {
+			log.Fatalf("Unable to open polar file: %v", err)
+		}
+		polar, err = analysis.ReadPolar(pf)
+		pf.Close()
+		if err != nil {
+			log.Fatalf("Unable to load polar: %v", err)
+		}
+	}
+
+	f, err := os.Open(*csvFile)
+	if err != nil {
+		log.Fatalf("Unable to open CSV file: %v", err)
+	}
+	defer f.Close()
+
+	data, err := datasource.NewReplayNavigationDataProvider(f, start, end)
+	if err != nil {
+		log.Fatalf("Unable to load data: %v", err)
+	}
+	points := data.GetAllPoints()
+
+	session := analysis.Analyze(points, detection)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprintln(w, "Leg\tStart\tEnd\tDuration\t")
+	for _, leg := range session.Legs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", leg.Type, leg.Start.Format("15:04:05"), leg.End.Format("15:04:05"),
+			leg.End.Sub(leg.Start).Truncate(time.Second))
+	}
+	fmt.Fprintln(w, "\t\t\t\t")
+
+	lossConfig := analysis.DefaultLossConfig()
+	lossConfig.LossWindow = *lossWindow
+	losses := analysis.ManeuverLosses(points, session.Events, polar, lossConfig)
+
+	fmt.Fprintln(w, "Maneuver\tTime\tEntry kn\tMin kn\tExit kn\tTurn s\tRecover s\tLost m\t")
+	totals := make(map[analysis.EventType][]analysis.ManeuverLoss)
+	for _, loss := range losses {
+		recovery := "-"
+		if loss.Recovered {
+			recovery = fmt.Sprintf("%.0f", loss.RecoveryTime.Seconds())
+		}
+		fmt.Fprintf(w, "%s\t%s\t%.2f\t%.2f\t%.2f\t%.0f\t%s\t%.1f\t\n",
+			loss.Event.Type, loss.Event.Timestamp.Format("15:04:05"),
+			loss.EntrySpeed, loss.MinSpeed, loss.ExitSpeed,
+			loss.TurnDuration().Seconds(), recovery, loss.MetersLost)
+		totals[loss.Event.Type] = append(totals[loss.Event.Type], loss)
+	}
+	fmt.Fprintln(w, "\t\t\t\t\t\t\t\t")
+
+	fmt.Fprintln(w, "Average\tCount\tEntry kn\tMin kn\tExit kn\tTurn s\t\tLost m\t")
+	for _, eventType := range []analysis.EventType{analysis.EventTack, analysis.EventGybe} {
+		typeLosses := totals[eventType]
+		if len(typeLosses) == 0 {
+			continue
+		}
+
+		var entry, minSpeed, exit, turn, lost float64
+		for _, loss := range typeLosses {
+			entry += loss.EntrySpeed
+			minSpeed += loss.MinSpeed
+			exit += loss.ExitSpeed
+			turn += loss.TurnDuration().Seconds()
+			lost += loss.MetersLost
+		}
+		n := float64(len(typeLosses))
+		fmt.Fprintf(w, "%s\t%d\t%.2f\t%.2f\t%.2f\t%.0f\t\t%.1f\t\n",
+			eventType, len(typeLosses), entry/n, minSpeed/n, exit/n, turn/n, lost/n)
+	}
+
+	if err := w.Flush(); err != nil {
+		log.Fatalf("Unable to write the report: %v", err)
+	}
+}
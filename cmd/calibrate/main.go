@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+var (
+	csvFile   = flag.String("csv", "", "CSV data file to fit the calibration from")
+	startTime = flag.String("start", "", "Start time of the calibration data (RFC3339 format)")
+	endTime   = flag.String("end", "", "End time of the calibration data (RFC3339 format)")
+	outFile   = flag.String("out", "", "File to write the calibration table to (JSON), stdout if empty")
+)
+
+func main() {
+	flag.Parse()
+
+	if *csvFile == "" {
+		log.Fatalf("Must provide -csv argument with the data file")
+	}
+
+	var start, end *time.Time
+	if *startTime != "" {
+		t, err := time.Parse(time.RFC3339, *startTime)
+		if err != nil {
+			log.Fatalf("Invalid start time format: %v", err)
+		}
+		start = &t
+	}
+	if *endTime != "" {
+		t, err := time.Parse(time.RFC3339, *endTime)
+		if err != nil {
+			log.Fatalf("Invalid end time format: %v", err)
+		}
+		end = &t
+	}
+
+	f, err := os.Open(*csvFile)
+	if err != nil {
+		log.Fatalf("Unable to open CSV file: %v", err)
+	}
+	defer f.Close()
+
+	data, err := datasource.NewReplayNavigationDataProvider(f, start, end)
+	if err != nil {
+		log.Fatalf("Unable to load data: %v", err)
+	}
+
+	calibration, err := datasource.EstimateCalibration(data)
+	if err != nil {
+		log.Fatalf("Unable to estimate calibration: %v", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Compass offset: %+.1f deg\n", calibration.CompassOffset)
+	fmt.Fprintf(os.Stderr, "AWA offset:     %+.1f deg\n", calibration.AWAOffset)
+	fmt.Fprintf(os.Stderr, "STW factor:     %.3f\n", calibration.STWFactor)
+	fmt.Fprintf(os.Stderr, "TWD tack error: %+.1f deg\n", calibration.TWDTackError)
+
+	out := os.Stdout
+	if *outFile != "" {
+		out, err = os.Create(*outFile)
+		if err != nil {
+			log.Fatalf("Unable to create output file: %v", err)
+		}
+		defer out.Close()
+	}
+
+	if err := calibration.Save(out); err != nil {
+		log.Fatalf("Unable to write calibration: %v", err)
+	}
+}
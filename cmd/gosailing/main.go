@@ -3,6 +3,9 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/gopxl/pixel/v2"
@@ -10,6 +13,7 @@ import (
 	"golang.org/x/image/colornames"
 
 	"github.io/mpihlak/gosailing"
+	"github.io/mpihlak/gosailing/datasource"
 )
 
 const (
@@ -20,6 +24,8 @@ const (
 	markLocationY = maxHeight - 50
 	boatLocationX = maxWidth / 2
 	boatLocationY = 25
+	// startLineY leaves room behind the start line for the start sequence
+	startLineY = 200
 )
 
 var (
@@ -27,8 +33,144 @@ var (
 	windAmplitude = flag.Float64("windShiftAmplitude", 10.0, "Amplitude of the wind shifts degrees")
 	windDirection = flag.Float64("windDirection", 0.0, "Starting wind direction degrees")
 	windShiftRate = flag.Float64("windShiftRate", 0.0, "Degrees per cycle to shift wind")
+	ghostFile     = flag.String("ghost", "", "Previous run recorded from the game to race against")
+	ghostLog      = flag.String("ghostLog", "", "CSV log of a real race to race against")
+	recordFile    = flag.String("record", "", "File to save the run to when finished, for racing against it later")
+	coursePreset  = flag.String("course", "upwind", "Course to race: upwind, windward-leeward or triangle")
+	laps          = flag.Int("laps", 1, "Number of laps of the windward-leeward and triangle courses")
+	startSequence = flag.Bool("start", false, "Race with a five minute start sequence")
+	seed          = flag.Int64("seed", 0, "Seed of the wind, 0 starts the wind from the beginning of its cycle")
+	randomSeed    = flag.Bool("randomSeed", false, "Pick the seed of the wind at random")
+	player        = flag.String("player", os.Getenv("USER"), "Player name for the leaderboard")
+	resultsFile   = flag.String("results", "gosailing-results.json", "File that the results of the races are kept in")
+	scenarioCode  = flag.String("scenario", "", "Scenario code to race, in place of the course, wind and seed settings")
 )
 
+// loadGhostTrack loads the ghost to race against from a game recording or a real log
+func loadGhostTrack() *gosailing.GhostTrack {
+	if *ghostFile == "" && *ghostLog == "" {
+		return nil
+	}
+
+	fileName := *ghostFile
+	if fileName == "" {
+		fileName = *ghostLog
+	}
+	f, err := os.Open(fileName)
+	if err != nil {
+		log.Fatalf("Unable to open ghost file: %v", err)
+	}
+	defer f.Close()
+
+	if *ghostFile != "" {
+		track, err := gosailing.ReadGhostTrack(f)
+		if err != nil {
+			log.Fatalf("Unable to load ghost: %v", err)
+		}
+		return track
+	}
+
+	logData, err := datasource.NewReplayNavigationDataProvider(f, nil, nil)
+	if err != nil {
+		log.Fatalf("Unable to load ghost log: %v", err)
+	}
+	track, err := gosailing.GhostTrackFromLog(logData.GetAllPoints(), boatLocationX, boatLocationY)
+	if err != nil {
+		log.Fatalf("Unable to create ghost from log: %v", err)
+	}
+	return track
+}
+
+// saveRecording saves the finished run so that it can be used as a ghost
+func saveRecording(sailRace *gosailing.SailRace) {
+	f, err := os.Create(*recordFile)
+	if err != nil {
+		log.Printf("Unable to save the run: %v", err)
+		return
+	}
+	defer f.Close()
+
+	if err := sailRace.Recording().Write(f); err != nil {
+		log.Printf("Unable to save the run: %v", err)
+		return
+	}
+	fmt.Printf("Saved the run to %v\n", *recordFile)
+}
+
+// loadScenario returns the scenario of the code given with -scenario, or of the settings
+func loadScenario() gosailing.Scenario {
+	if *scenarioCode != "" {
+		scenario, err := gosailing.ParseScenarioCode(*scenarioCode)
+		if err != nil {
+			log.Fatalf("Unable to load the scenario: %v", err)
+		}
+		if scenario.WindFile != "" && filepath.Base(*windData) != scenario.WindFile {
+			log.Fatalf("The scenario replays the wind of %s, give the file with -windData", scenario.WindFile)
+		}
+		return scenario
+	}
+
+	scenario := gosailing.Scenario{
+		Course:        *coursePreset,
+		Laps:          *laps,
+		StartSequence: *startSequence,
+		WindDirection: *windDirection,
+		WindAmplitude: *windAmplitude,
+		WindShiftRate: *windShiftRate,
+		Seed:          *seed,
+	}
+	if *windData != "" {
+		scenario.WindFile = filepath.Base(*windData)
+	}
+	if *randomSeed {
+		scenario.Seed = time.Now().UnixNano()%1000000 + 1
+	}
+	return scenario
+}
+
+// loadResults loads the results of the earlier races, there are none before the first race
+func loadResults() *gosailing.Results {
+	f, err := os.Open(*resultsFile)
+	if os.IsNotExist(err) {
+		return &gosailing.Results{}
+	}
+	if err != nil {
+		log.Fatalf("Unable to open results: %v", err)
+	}
+	defer f.Close()
+
+	results, err := gosailing.LoadResults(f)
+	if err != nil {
+		log.Fatalf("Unable to load results: %v", err)
+	}
+	return results
+}
+
+// saveResult adds the finished race to the results, saves them and shows the leaderboard
+func saveResult(sailRace *gosailing.SailRace, results *gosailing.Results, scenario gosailing.Scenario, code string) {
+	result := sailRace.Result()
+	result.Player = *player
+	if result.Player == "" {
+		result.Player = "anonymous"
+	}
+	result.Scenario = code
+	result.Seed = scenario.Seed
+	result.Date = time.Now()
+	results.Add(result)
+	sailRace.SetLeaderboard(results.Leaderboard(result.Scenario), result)
+
+	f, err := os.Create(*resultsFile)
+	if err != nil {
+		log.Printf("Unable to save the results: %v", err)
+		return
+	}
+	defer f.Close()
+
+	if err := results.Save(f); err != nil {
+		log.Printf("Unable to save the results: %v", err)
+	}
+}
+
 func run() {
 	cfg := opengl.WindowConfig{
 		Title:  "Go Sailing!",
@@ -40,24 +182,47 @@ func run() {
 		panic(err)
 	}
 
+	ghostTrack := loadGhostTrack()
+	results := loadResults()
+	scenario := loadScenario()
+	code, err := scenario.Code()
+	if err != nil {
+		log.Fatalf("Invalid scenario: %v", err)
+	}
+	fmt.Printf("Scenario %s: %s\n", code, scenario)
+
 	newSailRace := func() *gosailing.SailRace {
-		var windShifter gosailing.WindShifter
-		if *windData != "" {
-			fmt.Printf("Using wind data from %v\n", *windData)
-			windShifter = gosailing.NewReplayShifter(*windData)
-		} else {
-			fmt.Printf("Generating oscillating wind\n")
-			windShifter = gosailing.NewOscillatingWindShifter(*windDirection, *windAmplitude, 10, *windShiftRate)
-		}
+		windShifter := scenario.NewWindShifter(*windData)
 
-		return gosailing.NewSailRace(
+		sailRace := gosailing.NewSailRace(
 			markLocationX, markLocationY,
 			boatLocationX, boatLocationY,
 			windShifter,
 		)
+		startY := float64(boatLocationY)
+		if scenario.StartSequence {
+			startY = startLineY
+		}
+		course, err := gosailing.NewCourse(scenario.Course,
+			boatLocationX, startY,
+			markLocationX, markLocationY,
+			windShifter.GetWindDirection(), scenario.Laps,
+		)
+		if err != nil {
+			log.Fatalf("Unable to create the course: %v", err)
+		}
+		sailRace.SetCourse(course)
+		if scenario.StartSequence {
+			sailRace.SetStartSequence()
+		}
+		if ghostTrack != nil {
+			sailRace.SetGhost(ghostTrack)
+		}
+		return sailRace
 	}
 
 	sailRace := newSailRace()
+	saved := false
 
 	// Throttle the keyboard to avoid registering unintended repeated keypresses
 	lastKeyPressed := make(map[pixel.Button]time.Time)
@@ -83,6 +248,7 @@ func run() {
 			if sailRace.IsFinished() {
 				sailRace = newSailRace()
 				sailRace.StartRace()
+				saved = false
 			} else {
 				sailRace.TogglePause()
 			}
@@ -93,6 +259,13 @@ func run() {
 		if keyPressed(pixel.KeyR) {
 			sailRace = newSailRace()
 			sailRace.StartRace()
+			saved = false
+		}
+		if win.Pressed(pixel.KeyLeft) {
+			sailRace.SteerBoat(-2)
+		}
+		if win.Pressed(pixel.KeyRight) {
+			sailRace.SteerBoat(2)
 		}
 		if keyPressed(pixel.KeyL) {
 			sailRace.ToggleLaylines()
@@ -105,6 +278,14 @@ func run() {
 		sailRace.Update(win)
 		win.Update()
 
+		if sailRace.IsFinished() && !saved {
+			if *recordFile != "" {
+				saveRecording(sailRace)
+			}
+			saveResult(sailRace, results, scenario, code)
+			saved = true
+		}
+
 		sailRace.Throttle()
 	}
 }
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+var (
+	csvFile    = flag.String("csv", "", "CSV log file")
+	nmeaFile   = flag.String("nmea", "", "NMEA 0183 log file")
+	gpxFile    = flag.String("gpx", "", "GPX track file")
+	signalK    = flag.String("signalk", "", "Signal K delta file or server stream URL (ws:// or wss://)")
+	csvOffset  = flag.Duration("csvOffset", 0, "Clock offset added to the CSV timestamps")
+	nmeaOffset = flag.Duration("nmeaOffset", 0, "Clock offset added to the NMEA timestamps")
+	gpxOffset  = flag.Duration("gpxOffset", 0, "Clock offset added to the GPX timestamps")
+	skOffset   = flag.Duration("signalkOffset", 0, "Clock offset added to the Signal K timestamps")
+	precedence = flag.String("precedence", "", "Per field source precedence, eg. \"hdg=nmea,csv;position=gpx\"")
+	interval   = flag.Duration("interval", 0, "Interval of the merged points, every source timestamp if zero")
+	maxAge     = flag.Duration("maxAge", 0, "Maximum age of a source value to be used in a merged point")
+	outFile    = flag.String("out", "", "Merged CSV output file, stdout if empty")
+)
+
+// gpxFields are the fields that a GPX track can provide, the rest are left to the other sources
+var gpxFields = []datasource.Field{
+	datasource.FieldPosition,
+	datasource.FieldCourseOverGround,
+	datasource.FieldSpeedOverGround,
+	datasource.FieldCumulativeDistance,
+}
+
+func main() {
+	flag.Parse()
+
+	var sources []datasource.MergeSource
+
+	if *csvFile != "" {
+		f, err := os.Open(*csvFile)
+		if err != nil {
+			log.Fatalf("Unable to open CSV file: %v", err)
+		}
+		defer f.Close()
+
+		provider, err := datasource.NewReplayNavigationDataProvider(f, nil, nil)
+		if err != nil {
+			log.Fatalf("Unable to load CSV file: %v", err)
+		}
+		sources = append(sources, datasource.MergeSource{Name: "csv", Provider: provider, ClockOffset: *csvOffset})
+	}
+
+	if *nmeaFile != "" {
+		f, err := os.Open(*nmeaFile)
+		if err != nil {
+			log.Fatalf("Unable to open NMEA file: %v", err)
+		}
+		defer f.Close()
+
+		provider := datasource.NewNMEANavigationDataProvider(f)
+		sources = append(sources, datasource.MergeSource{Name: "nmea", Provider: provider, ClockOffset: *nmeaOffset})
+	}
+
+	if *gpxFile != "" {
+		f, err := os.Open(*gpxFile)
+		if err != nil {
+			log.Fatalf("Unable to open GPX file: %v", err)
+		}
+		defer f.Close()
+
+		provider, err := datasource.NewGPXNavigationDataProvider(f)
+		if err != nil {
+			log.Fatalf("Unable to load GPX file: %v", err)
+		}
+		sources = append(sources, datasource.MergeSource{Name: "gpx", Provider: provider, ClockOffset: *gpxOffset, Fields: gpxFields})
+	}
+
+	if *signalK != "" {
+		var provider *datasource.SignalKNavigationDataProvider
+		if strings.HasPrefix(*signalK, "ws://") || strings.HasPrefix(*signalK, "wss://") {
+			var err error
+			provider, err = datasource.DialSignalK(*signalK)
+			if err != nil {
+				log.Fatalf("Unable to connect to Signal K server: %v", err)
+			}
+
+			// Record the live stream until interrupted, then merge what was received
+			log.Printf("Recording Signal K stream, press Ctrl-C to stop")
+			interrupted := make(chan os.Signal, 1)
+			signal.Notify(interrupted, os.Interrupt)
+			go func() {
+				<-interrupted
+				provider.Close()
+			}()
+		} else {
+			f, err := os.Open(*signalK)
+			if err != nil {
+				log.Fatalf("Unable to open Signal K file: %v", err)
+			}
+			defer f.Close()
+
+			provider = datasource.NewSignalKNavigationDataProvider(f)
+		}
+		sources = append(sources, datasource.MergeSource{Name: "signalk", Provider: provider, ClockOffset: *skOffset})
+	}
+
+	if len(sources) == 0 {
+		log.Fatalf("Must provide at least one of -csv, -nmea, -gpx or -signalk")
+	}
+
+	fieldPrecedence, err := datasource.ParsePrecedence(*precedence)
+	if err != nil {
+		log.Fatalf("Invalid precedence: %v", err)
+	}
+
+	merged, err := datasource.NewMergingNavigationDataProvider(datasource.MergeConfig{
+		Sources:    sources,
+		Precedence: fieldPrecedence,
+		Interval:   *interval,
+		MaxAge:     *maxAge,
+	})
+	if err != nil {
+		log.Fatalf("Unable to merge logs: %v", err)
+	}
+
+	out := os.Stdout
+	if *outFile != "" {
+		out, err = os.Create(*outFile)
+		if err != nil {
+			log.Fatalf("Unable to create output file: %v", err)
+		}
+		defer out.Close()
+	}
+
+	if err := datasource.WriteCSV(out, merged.GetAllPoints()); err != nil {
+		log.Fatalf("Unable to write merged log: %v", err)
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"flag"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/gopxl/pixel/v2"
 	"github.com/gopxl/pixel/v2/backends/opengl"
 	"github.io/mpihlak/gosailing"
+	"github.io/mpihlak/gosailing/analysis"
 	"github.io/mpihlak/gosailing/datasource"
 	"golang.org/x/image/colornames"
 )
@@ -19,45 +22,85 @@ const (
 )
 
 var (
-	csvFile   = flag.String("csv", "", "CSV data file to replay")
-	startTime = flag.String("start", "", "Start time to replay from (RFC3339 format)")
-	endTime   = flag.String("end", "", "End time to replay to (RFC3339 format)")
-	markLat   = flag.Float64("markLat", 0, "Latitude of the mark")
-	markLng   = flag.Float64("markLng", 0, "Longitude of the mark")
-	zoomLevel = flag.Float64("zoom", 5500, "Zoom level")
+	csvFiles   = flag.String("csv", "", "CSV data files to replay, comma separated. The first one is our own boat")
+	boatNames  = flag.String("names", "", "Names of the boats, comma separated in the order of the CSV files")
+	startTime  = flag.String("start", "", "Start time to replay from (RFC3339 format)")
+	endTime    = flag.String("end", "", "End time to replay to (RFC3339 format)")
+	markLat    = flag.Float64("markLat", 0, "Latitude of the mark, inferred from the roundings in the logs if not set")
+	markLng    = flag.Float64("markLng", 0, "Longitude of the mark, inferred from the roundings in the logs if not set")
+	scale      = flag.Float64("scale", 0, "Scale of the replay in metres per pixel, the whole session is fitted to the window if not set")
+	polarFile  = flag.String("polar", "", "Boat polar table for the target speeds and laylines")
+	panel      = flag.String("panel", gosailing.DefaultInstrumentPanel, "Instruments to show, comma separated")
+	calFiles   = flag.String("calibration", "", "Instrument calibration tables (JSON) to apply to the data, comma separated in the order of the CSV files")
+	courseFile = flag.String("course", "", "Course definition file (JSON or GPX waypoints) with the start line, marks and finish line")
 )
 
 func run() {
-	if *csvFile == "" {
+	if *csvFiles == "" {
 		log.Fatalf("Must provide -csv argument with replay file")
 	}
-	if *markLat == 0 || *markLng == 0 {
-		log.Fatalf("Must provide -markLat and -markLng arguments with mark location")
-	}
 
-	var start, end time.Time
-	var err error
+	var start, end *time.Time
 	if *startTime != "" {
-		start, err = time.Parse(time.RFC3339, *startTime)
+		t, err := time.Parse(time.RFC3339, *startTime)
 		if err != nil {
 			log.Fatalf("Invalid start time format: %v", err)
 		}
+		start = &t
 	}
 	if *endTime != "" {
-		end, err = time.Parse(time.RFC3339, *endTime)
+		t, err := time.Parse(time.RFC3339, *endTime)
 		if err != nil {
 			log.Fatalf("Invalid end time format: %v", err)
 		}
+		end = &t
 	}
 
-	f, err := os.Open(*csvFile)
-	if err != nil {
-		log.Fatalf("Unable to open CSV file: %v", err)
+	files := strings.Split(*csvFiles, ",")
+	names := splitList(*boatNames)
+	calibrations := splitList(*calFiles)
+
+	var fleet []gosailing.ReplayBoat
+	for i, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		calFile := ""
+		if i < len(calibrations) {
+			calFile = calibrations[i]
+		}
+
+		fleet = append(fleet, gosailing.ReplayBoat{
+			Name: name,
+			Data: loadReplayData(file, calFile, start, end),
+		})
 	}
 
-	replayData, err := datasource.NewReplayNavigationDataProvider(f, &start, &end)
-	if err != nil {
-		log.Fatal("Unable to load replay")
+	var course *datasource.Course
+	if *courseFile != "" {
+		cf, err := os.Open(*courseFile)
+		if err != nil {
+			log.Fatalf("Unable to open course file: %v", err)
+		}
+		course, err = datasource.LoadCourse(cf)
+		cf.Close()
+		if err != nil {
+			log.Fatalf("Unable to load course: %v", err)
+		}
+		first := course.Marks[0].Target()
+		*markLat, *markLng = first.Latitude, first.Longitude
+	}
+
+	if *markLat == 0 || *markLng == 0 {
+		mark, ok := inferMark(fleet)
+		if !ok {
+			log.Fatalf("Unable to infer the mark location from the logs, provide -markLat and -markLng arguments")
+		}
+		log.Printf("Inferred the %s mark from %d roundings: -markLat %.6f -markLng %.6f",
+			mark.Type, mark.Roundings, mark.Latitude, mark.Longitude)
+		*markLat, *markLng = mark.Latitude, mark.Longitude
 	}
 
 	cfg := opengl.WindowConfig{
@@ -80,33 +123,133 @@ func run() {
 		return false
 	}
 
-	rr, err := gosailing.NewRaceReplay(*markLat, *markLng, maxWidth, maxHeight, *zoomLevel, replayData)
+	rr, err := gosailing.NewRaceReplay(*markLat, *markLng, maxWidth, maxHeight, *scale, fleet)
 	if err != nil {
 		log.Fatalf("Unable to create race replay: %v", err)
 	}
 
-	for !win.Closed() {
-		if keyPressed(pixel.KeyQ) || keyPressed(pixel.KeyEscape) {
-			break
-		}
-		if keyPressed(pixel.KeySpace) || keyPressed(pixel.KeyP) {
-			rr.TogglePause()
-		}
-		if keyPressed(pixel.KeyR) {
-			rr.StartReplay()
-		}
-		if keyPressed(pixel.Key1) {
-			rr.IncreaseSpeed()
-		}
-		if keyPressed(pixel.Key2) {
-			rr.DecreaseSpeed()
+	instruments, err := gosailing.ParseInstrumentPanel(*panel)
+	if err != nil {
+		log.Fatalf("Invalid instrument panel: %v", err)
+	}
+	rr.SetInstrumentPanel(instruments)
+
+	if course != nil {
+		rr.SetCourse(course)
+	}
+
+	bookmarksFile := datasource.BookmarksFile(files[0])
+	rr.SetBookmarks(loadBookmarks(bookmarksFile), func(bookmarks datasource.Bookmarks) {
+		saveBookmarks(bookmarksFile, bookmarks)
+	})
+
+	if *polarFile != "" {
+		pf, err := os.Open(*polarFile)
+		if err != nil {
+			log.Fatalf("Unable to open polar file: %v", err)
 		}
-		if keyPressed(pixel.KeyL) {
-			rr.ToggleLaylines()
+		polar, err := analysis.ReadPolar(pf)
+		pf.Close()
+		if err != nil {
+			log.Fatalf("Unable to load polar: %v", err)
 		}
-		if keyPressed(pixel.KeyW) {
-			rr.ToggleWindDirection()
+		rr.SetPolar(polar)
+	}
+
+	for !win.Closed() {
+		if rr.EditingNote() {
+			// The keys are typed into the note while it is being edited
+			rr.HandleTyping(win)
+			if !rr.EditingNote() {
+				lastKeyPressed[pixel.KeyEscape] = time.Now()
+			}
+		} else {
+			if keyPressed(pixel.KeyQ) || keyPressed(pixel.KeyEscape) {
+				break
+			}
+			if keyPressed(pixel.KeySpace) || keyPressed(pixel.KeyP) {
+				rr.TogglePause()
+			}
+			if keyPressed(pixel.KeyR) {
+				rr.StartReplay()
+			}
+			if keyPressed(pixel.Key1) {
+				rr.IncreaseSpeed()
+			}
+			if keyPressed(pixel.Key2) {
+				rr.DecreaseSpeed()
+			}
+			if keyPressed(pixel.KeyL) {
+				rr.ToggleLaylines()
+			}
+			if keyPressed(pixel.KeyW) {
+				rr.ToggleWindDirection()
+			}
+			if keyPressed(pixel.KeyC) {
+				rr.ToggleChannels()
+			}
+			if keyPressed(pixel.KeyE) {
+				rr.ToggleEvents()
+			}
+			if keyPressed(pixel.KeyLeftBracket) {
+				rr.PreviousEvent()
+			}
+			if keyPressed(pixel.KeyRightBracket) {
+				rr.NextEvent()
+			}
+			if keyPressed(pixel.KeyComma) {
+				rr.StepFrames(-1)
+			}
+			if keyPressed(pixel.KeyPeriod) {
+				rr.StepFrames(1)
+			}
+			if keyPressed(pixel.KeyLeft) {
+				rr.Skip(-10 * time.Second)
+			}
+			if keyPressed(pixel.KeyRight) {
+				rr.Skip(10 * time.Second)
+			}
+			if keyPressed(pixel.KeyDown) {
+				rr.Skip(-60 * time.Second)
+			}
+			if keyPressed(pixel.KeyUp) {
+				rr.Skip(60 * time.Second)
+			}
+			if keyPressed(pixel.KeyG) {
+				rr.ToggleCharts()
+			}
+			if keyPressed(pixel.KeyM) {
+				rr.StartNote()
+			}
+			if keyPressed(pixel.KeyK) {
+				rr.ToggleBookmarks()
+			}
+			if keyPressed(pixel.KeyPageUp) {
+				rr.PreviousBookmark()
+			}
+			if keyPressed(pixel.KeyPageDown) {
+				rr.NextBookmark()
+			}
+			if keyPressed(pixel.KeyDelete) {
+				rr.RemoveBookmark()
+			}
+			if keyPressed(pixel.KeyB) {
+				rr.Branch()
+			}
+			if keyPressed(pixel.KeyT) {
+				rr.TackBranch()
+			}
+			if keyPressed(pixel.KeyA) {
+				rr.ToggleAutoTack()
+			}
+			if keyPressed(pixel.KeyF) {
+				rr.ToggleFollow()
+			}
+			if keyPressed(pixel.KeyZ) {
+				rr.FitView()
+			}
 		}
+		rr.HandleMouse(win)
 
 		win.Clear(colornames.Lightblue)
 		rr.Update(win)
@@ -116,6 +259,91 @@ func run() {
 	}
 }
 
+// loadReplayData reads the CSV file and applies the calibration table if one is given
+func loadReplayData(csvFile, calFile string, start, end *time.Time) datasource.SeekableNavigationDataProvider {
+	f, err := os.Open(csvFile)
+	if err != nil {
+		log.Fatalf("Unable to open CSV file: %v", err)
+	}
+	defer f.Close()
+
+	csvData, err := datasource.NewReplayNavigationDataProvider(f, start, end)
+	if err != nil {
+		log.Fatalf("Unable to load replay %s: %v", csvFile, err)
+	}
+
+	if calFile == "" {
+		return csvData
+	}
+
+	cf, err := os.Open(calFile)
+	if err != nil {
+		log.Fatalf("Unable to open calibration file: %v", err)
+	}
+	defer cf.Close()
+
+	calibration, err := datasource.LoadCalibration(cf)
+	if err != nil {
+		log.Fatalf("Unable to load calibration: %v", err)
+	}
+
+	return datasource.NewSeekableNavigationDataProvider(
+		datasource.NewCalibratedNavigationDataProvider(csvData, calibration))
+}
+
+// loadBookmarks reads the bookmarks from the sidecar file of the log, if there is one
+func loadBookmarks(fileName string) datasource.Bookmarks {
+	f, err := os.Open(fileName)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		log.Fatalf("Unable to open bookmarks file: %v", err)
+	}
+	defer f.Close()
+
+	bookmarks, err := datasource.LoadBookmarks(f)
+	if err != nil {
+		log.Fatalf("Unable to load bookmarks: %v", err)
+	}
+	return bookmarks
+}
+
+func saveBookmarks(fileName string, bookmarks datasource.Bookmarks) {
+	f, err := os.Create(fileName)
+	if err != nil {
+		log.Printf("Unable to save the bookmarks: %v", err)
+		return
+	}
+	defer f.Close()
+
+	if err := bookmarks.Save(f); err != nil {
+		log.Printf("Unable to save the bookmarks: %v", err)
+		return
+	}
+	log.Printf("Saved %d bookmarks to %v", len(bookmarks), fileName)
+}
+
+// inferMark finds the windward mark from the roundings of all the boats
+func inferMark(fleet []gosailing.ReplayBoat) (analysis.MarkEstimate, bool) {
+	sessions := make([][]datasource.NavigationDataPoint, len(fleet))
+	for i, rb := range fleet {
+		sessions[i] = make([]datasource.NavigationDataPoint, rb.Data.Len())
+		for j := range sessions[i] {
+			sessions[i][j] = rb.Data.At(j)
+		}
+	}
+	return analysis.InferWindwardMark(sessions, analysis.DefaultMarkConfig())
+}
+
+// splitList splits a comma separated flag value, an empty value gives an empty list
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
 func main() {
 	flag.Parse()
 	opengl.Run(run)
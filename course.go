@@ -0,0 +1,258 @@
+package gosailing
+
+import (
+	"fmt"
+	"math"
+
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+// Sizes of the course presets in pixels
+const (
+	startLineHalfWidth  = 100.0
+	gateHalfWidth       = 40.0
+	finishLineHalfWidth = 60.0
+)
+
+// CourseLine is a start or finish line on the screen between the committee boat and the pin
+type CourseLine struct {
+	BoatX float64
+	BoatY float64
+	PinX  float64
+	PinY  float64
+}
+
+// Center returns the middle of the line
+func (l CourseLine) Center() (float64, float64) {
+	return (l.BoatX + l.PinX) / 2, (l.BoatY + l.PinY) / 2
+}
+
+// Crossed returns true if sailing from x1, y1 to x2, y2 crosses the line, in either direction
+func (l CourseLine) Crossed(x1, y1, x2, y2 float64) bool {
+	// The ends of each segment must be on the opposite sides of the other one
+	d1 := cross(l.PinX, l.PinY, l.BoatX, l.BoatY, x1, y1)
+	d2 := cross(l.PinX, l.PinY, l.BoatX, l.BoatY, x2, y2)
+	d3 := cross(x1, y1, x2, y2, l.PinX, l.PinY)
+	d4 := cross(x1, y1, x2, y2, l.BoatX, l.BoatY)
+	return ((d1 < 0 && d2 >= 0) || (d1 >= 0 && d2 < 0)) && ((d3 < 0 && d4 >= 0) || (d3 >= 0 && d4 < 0))
+}
+
+// cross returns the z component of the cross product of a->b and a->p, its sign tells on which
+// side of the line through a and b the point p is
+func cross(ax, ay, bx, by, px, py float64) float64 {
+	return (bx-ax)*(py-ay) - (by-ay)*(px-ax)
+}
+
+// Course is a multi-leg race course of the game in screen coordinates. The marks of a lap are
+// rounded in order on every lap and the race ends by crossing the finish line.
+type Course struct {
+	Name  string
+	Start CourseLine
+	// Marks are the marks of one lap in the order they are rounded
+	Marks  []CourseMark
+	Laps   int
+	Finish CourseLine
+}
+
+// UpwindCourse is a single beat from the start to a finish line that starts at the mark and
+// extends to the right of it, looking upwind
+func UpwindCourse(startX, startY, markX, markY, windDirection float64) *Course {
+	c := &Course{
+		Name:  "Upwind",
+		Start: lineAcross(startX, startY, startLineHalfWidth, windDirection),
+		Laps:  1,
+	}
+	boatX, boatY := RotatePoint(markX+2*startLineHalfWidth, markY, markX, markY, windDirection)
+	c.Finish = CourseLine{BoatX: boatX, BoatY: boatY, PinX: markX, PinY: markY}
+	return c
+}
+
+// WindwardLeewardCourse has laps of a windward mark and a leeward gate, with the start line
+// centered at startX, startY and the windward mark at the distance length upwind of it. The
+// finish line is a little below the windward mark.
+func WindwardLeewardCourse(startX, startY, length, windDirection float64, laps int) *Course {
+	windwardX, windwardY := upwindPoint(startX, startY, 0, length, windDirection)
+	gateX, gateY := upwindPoint(startX, startY, -gateHalfWidth, 0.15*length, windDirection)
+	gateEndX, gateEndY := upwindPoint(startX, startY, gateHalfWidth, 0.15*length, windDirection)
+	finishX, finishY := upwindPoint(startX, startY, 0, 0.8*length, windDirection)
+
+	return &Course{
+		Name:  "Windward-leeward",
+		Start: lineAcross(startX, startY, startLineHalfWidth, windDirection),
+		Marks: []CourseMark{
+			{Name: "Windward", X: windwardX, Y: windwardY, Rounding: datasource.RoundingPort},
+			{Name: "Leeward gate", X: gateX, Y: gateY, IsGate: true, GateX: gateEndX, GateY: gateEndY},
+		},
+		Laps:   max(1, laps),
+		Finish: lineAcross(finishX, finishY, finishLineHalfWidth, windDirection),
+	}
+}
+
+// TriangleCourse has laps of a windward, a wing and a leeward mark, all rounded to port. The
+// wing mark is half way up the course on its left.
+func TriangleCourse(startX, startY, length, windDirection float64, laps int) *Course {
+	windwardX, windwardY := upwindPoint(startX, startY, 0, length, windDirection)
+	wingX, wingY := upwindPoint(startX, startY, -length/2, length/2, windDirection)
+	leewardX, leewardY := upwindPoint(startX, startY, 0, 0.15*length, windDirection)
+	finishX, finishY := upwindPoint(startX, startY, 0, 0.8*length, windDirection)
+
+	return &Course{
+		Name:  "Triangle",
+		Start: lineAcross(startX, startY, startLineHalfWidth, windDirection),
+		Marks: []CourseMark{
+			{Name: "Windward", X: windwardX, Y: windwardY, Rounding: datasource.RoundingPort},
+			{Name: "Wing", X: wingX, Y: wingY, Rounding: datasource.RoundingPort},
+			{Name: "Leeward", X: leewardX, Y: leewardY, Rounding: datasource.RoundingPort},
+		},
+		Laps:   max(1, laps),
+		Finish: lineAcross(finishX, finishY, finishLineHalfWidth, windDirection),
+	}
+}
+
+// CoursePresets are the names of the courses that NewCourse creates
+var CoursePresets = []string{"upwind", "windward-leeward", "triangle"}
+
+// NewCourse creates a course preset by name: "upwind", "windward-leeward" or "triangle"
+func NewCourse(preset string, startX, startY, markX, markY, windDirection float64, laps int) (*Course, error) {
+	length := math.Hypot(markX-startX, markY-startY)
+	switch preset {
+	case "upwind":
+		return UpwindCourse(startX, startY, markX, markY, windDirection), nil
+	case "windward-leeward":
+		return WindwardLeewardCourse(startX, startY, length, windDirection, laps), nil
+	case "triangle":
+		return TriangleCourse(startX, startY, length, windDirection, laps), nil
+	}
+	return nil, fmt.Errorf("unknown course: %s", preset)
+}
+
+// upwindPoint returns the point that is across to the right and up the wind from x, y
+func upwindPoint(x, y, across, up, windDirection float64) (float64, float64) {
+	return RotatePoint(x+across, y+up, x, y, windDirection)
+}
+
+// lineAcross returns a line centered at x, y that is square to the wind, with the pin on the left
+func lineAcross(x, y, halfWidth, windDirection float64) CourseLine {
+	boatX, boatY := upwindPoint(x, y, halfWidth, 0, windDirection)
+	pinX, pinY := upwindPoint(x, y, -halfWidth, 0, windDirection)
+	return CourseLine{BoatX: boatX, BoatY: boatY, PinX: pinX, PinY: pinY}
+}
+
+// Legs returns the number of legs, one to every mark of every lap and the last one to the finish
+func (c *Course) Legs() int {
+	return len(c.Marks)*c.Laps + 1
+}
+
+// IsFinishLeg returns true if the leg ends at the finish line
+func (c *Course) IsFinishLeg(leg int) bool {
+	return leg >= c.Legs()-1
+}
+
+// Mark returns the mark at the end of the leg, false for the leg to the finish
+func (c *Course) Mark(leg int) (CourseMark, bool) {
+	if c.IsFinishLeg(leg) || len(c.Marks) == 0 {
+		return CourseMark{}, false
+	}
+	return c.Marks[leg%len(c.Marks)], true
+}
+
+// LegName returns the name of the mark at the end of the leg
+func (c *Course) LegName(leg int) string {
+	if m, ok := c.Mark(leg); ok {
+		return m.Name
+	}
+	return "Finish"
+}
+
+// Target returns the location the boats sail to on the leg: the mark, or the middle of the gate
+// or the finish line
+func (c *Course) Target(leg int) (float64, float64) {
+	if leg < 0 {
+		return c.Start.Center()
+	}
+	m, ok := c.Mark(leg)
+	switch {
+	case !ok:
+		return c.Finish.Center()
+	case m.IsGate:
+		return (m.X + m.GateX) / 2, (m.Y + m.GateY) / 2
+	}
+	return m.X, m.Y
+}
+
+// Downwind returns true if the target of the leg is downwind from x, y
+func (c *Course) Downwind(leg int, x, y, windDirection float64) bool {
+	targetX, targetY := c.Target(leg)
+	_, up := RotatePoint(targetX, targetY, x, y, -windDirection)
+	return up < y
+}
+
+// Pass returns true if sailing from x1, y1 to x2, y2 passes the end of the leg, and for marks the
+// side of the boat that faced the mark. The finish line and gates are passed by crossing them
+// coming from the previous mark. A mark is passed by crossing the ray from it that points away
+// from both of its legs, or the line through it that is square to the leg when the course goes
+// straight past it.
+func (c *Course) Pass(leg int, x1, y1, x2, y2 float64) (string, bool) {
+	fromX, fromY := c.Target(leg - 1)
+	m, ok := c.Mark(leg)
+	switch {
+	case !ok:
+		return "", c.Finish.Crossed(x1, y1, x2, y2) && sameSide(c.Finish, fromX, fromY, x1, y1)
+	case m.IsGate:
+		gate := CourseLine{BoatX: m.GateX, BoatY: m.GateY, PinX: m.X, PinY: m.Y}
+		return "", gate.Crossed(x1, y1, x2, y2) && sameSide(gate, fromX, fromY, x1, y1)
+	}
+
+	// Directions back along the leg to the mark and on along the next leg
+	toX, toY := c.Target(leg + 1)
+	backX, backY := unit(fromX-m.X, fromY-m.Y)
+	onX, onY := unit(toX-m.X, toY-m.Y)
+	rayX, rayY := -(backX + onX), -(backY + onY)
+	straight := math.Hypot(rayX, rayY) < 0.1
+	if straight {
+		rayX, rayY = -backY, backX
+	}
+
+	side1 := cross(0, 0, rayX, rayY, x1-m.X, y1-m.Y)
+	side2 := cross(0, 0, rayX, rayY, x2-m.X, y2-m.Y)
+	if (side1 < 0) == (side2 < 0) || side1 == side2 {
+		return "", false
+	}
+	f := side1 / (side1 - side2)
+	along := (x1+(x2-x1)*f-m.X)*rayX + (y1+(y2-y1)*f-m.Y)*rayY
+	if along < 0 && !straight {
+		return "", false
+	}
+
+	// Going anticlockwise around the mark keeps it on the port side
+	if (side1 < 0) == (along >= 0) {
+		return datasource.RoundingPort, true
+	}
+	return datasource.RoundingStarboard, true
+}
+
+// sameSide returns true if the points are on the same side of the line
+func sameSide(l CourseLine, ax, ay, bx, by float64) bool {
+	return (cross(l.PinX, l.PinY, l.BoatX, l.BoatY, ax, ay) < 0) == (cross(l.PinX, l.PinY, l.BoatX, l.BoatY, bx, by) < 0)
+}
+
+func unit(x, y float64) (float64, float64) {
+	length := math.Hypot(x, y)
+	if length == 0 {
+		return 0, 0
+	}
+	return x / length, y / length
+}
+
+// Remaining returns the distance still to sail from x, y on the leg, to the target of the leg
+// and along the rest of the legs
+func (c *Course) Remaining(leg int, x, y float64) float64 {
+	targetX, targetY := c.Target(leg)
+	remaining := math.Hypot(targetX-x, targetY-y)
+	for l := leg + 1; l < c.Legs(); l++ {
+		fromX, fromY := c.Target(l - 1)
+		toX, toY := c.Target(l)
+		remaining += math.Hypot(toX-fromX, toY-fromY)
+	}
+	return remaining
+}
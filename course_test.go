@@ -0,0 +1,97 @@
+package gosailing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+func TestCourseLineCrossed(t *testing.T) {
+	require := require.New(t)
+
+	line := CourseLine{BoatX: 100, BoatY: 0, PinX: -100, PinY: 0}
+	require.True(line.Crossed(0, -1, 0, 1))
+	require.True(line.Crossed(50, 1, 50, -1))
+	require.False(line.Crossed(0, 1, 0, 2))
+	// Passing outside of the ends doesn't count
+	require.False(line.Crossed(150, -1, 150, 1))
+}
+
+func TestWindwardLeewardCourse(t *testing.T) {
+	require := require.New(t)
+
+	course := WindwardLeewardCourse(500, 0, 600, 0, 2)
+	require.Equal(5, course.Legs())
+	require.Equal("Windward", course.LegName(2))
+	require.Equal("Leeward gate", course.LegName(3))
+	require.Equal("Finish", course.LegName(4))
+
+	x, y := course.Target(0)
+	require.InDelta(500, x, 0.001)
+	require.InDelta(600, y, 0.001)
+	x, y = course.Target(1)
+	require.InDelta(500, x, 0.001)
+	require.InDelta(90, y, 0.001)
+
+	require.False(course.Downwind(0, 500, 0, 0))
+	require.True(course.Downwind(1, 500, 600, 0))
+
+	// The windward mark is passed by going around above it, the gate by sailing through it
+	// coming down from the windward mark
+	_, ok := course.Pass(0, 520, 598, 520, 599.5)
+	require.False(ok)
+	_, ok = course.Pass(0, 520, 598, 520, 610)
+	require.False(ok)
+	side, ok := course.Pass(0, 505, 610, 495, 610)
+	require.True(ok)
+	require.Equal(datasource.RoundingPort, side)
+	side, ok = course.Pass(0, 495, 610, 505, 610)
+	require.True(ok)
+	require.Equal(datasource.RoundingStarboard, side)
+
+	_, ok = course.Pass(1, 600, 91, 600, 89)
+	require.False(ok)
+	_, ok = course.Pass(1, 510, 89, 510, 91)
+	require.False(ok)
+	_, ok = course.Pass(1, 510, 91, 510, 89)
+	require.True(ok)
+
+	require.InDelta(100+3*510+390, course.Remaining(0, 500, 500), 0.001)
+}
+
+func TestCourseRotatesWithTheWind(t *testing.T) {
+	require := require.New(t)
+
+	// Wind from the right, the course is laid to the right of the start
+	course := TriangleCourse(0, 0, 400, 90, 1)
+	x, y := course.Target(0)
+	require.InDelta(400, x, 0.001)
+	require.InDelta(0, y, 0.001)
+
+	require.InDelta(0, course.Start.BoatX, 0.001)
+	require.InDelta(-100, course.Start.BoatY, 0.001)
+
+	finishX, finishY := course.Target(3)
+	require.InDelta(320, finishX, 0.001)
+	require.InDelta(0, finishY, 0.001)
+	_, ok := course.Pass(3, 319, 10, 321, 10)
+	require.True(ok)
+	_, ok = course.Pass(3, 321, 10, 319, 10)
+	require.False(ok)
+}
+
+func TestUpwindCourse(t *testing.T) {
+	require := require.New(t)
+
+	course := UpwindCourse(512, 25, 512, 718, 0)
+	require.Equal(1, course.Legs())
+	_, ok := course.Pass(0, 530, 717.5, 529, 718.5)
+	require.True(ok)
+	// Passing the mark on its left is not a finish
+	_, ok = course.Pass(0, 500, 717.5, 499, 718.5)
+	require.False(ok)
+
+	_, err := NewCourse("olympic", 512, 25, 512, 718, 0, 1)
+	require.Error(err)
+}
@@ -0,0 +1,33 @@
+package datasource
+
+import "math"
+
+// NormalizeAngle returns the angle in degrees normalized to the range (-180, 180]
+func NormalizeAngle(degrees float64) float64 {
+	a := math.Mod(degrees, 360)
+	if a <= -180 {
+		a += 360
+	} else if a > 180 {
+		a -= 360
+	}
+	return a
+}
+
+// NormalizeDirection returns the direction in degrees normalized to the range [0, 360)
+func NormalizeDirection(degrees float64) float64 {
+	a := math.Mod(degrees, 360)
+	if a < 0 {
+		a += 360
+	}
+	return a
+}
+
+// MeanAngle returns the circular mean of the angles in degrees, normalized to (-180, 180]
+func MeanAngle(angles []float64) float64 {
+	var sumSin, sumCos float64
+	for _, a := range angles {
+		sumSin += math.Sin(a * math.Pi / 180)
+		sumCos += math.Cos(a * math.Pi / 180)
+	}
+	return NormalizeAngle(math.Atan2(sumSin, sumCos) * 180 / math.Pi)
+}
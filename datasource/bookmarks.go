@@ -0,0 +1,56 @@
+package datasource
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Bookmark is a note at a moment of a log
+type Bookmark struct {
+	Time time.Time `json:"time"`
+	Note string    `json:"note"`
+}
+
+// Bookmarks are the notes of a log in time order
+type Bookmarks []Bookmark
+
+// BookmarksFile returns the name of the sidecar file of the log that the bookmarks are kept in
+func BookmarksFile(logFile string) string {
+	return strings.TrimSuffix(logFile, filepath.Ext(logFile)) + ".bookmarks.json"
+}
+
+// LoadBookmarks reads JSON encoded bookmarks
+func LoadBookmarks(reader io.Reader) (Bookmarks, error) {
+	var b Bookmarks
+	if err := json.NewDecoder(reader).Decode(&b); err != nil {
+		return nil, err
+	}
+	b.sort()
+	return b, nil
+}
+
+// Save writes the bookmarks as JSON
+func (b Bookmarks) Save(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(b)
+}
+
+// Add adds a bookmark and keeps the bookmarks in time order
+func (b *Bookmarks) Add(t time.Time, note string) {
+	*b = append(*b, Bookmark{Time: t, Note: note})
+	b.sort()
+}
+
+// Remove removes the bookmark at the index
+func (b *Bookmarks) Remove(i int) {
+	*b = append((*b)[:i], (*b)[i+1:]...)
+}
+
+func (b Bookmarks) sort() {
+	sort.SliceStable(b, func(i, j int) bool { return b[i].Time.Before(b[j].Time) })
+}
@@ -0,0 +1,37 @@
+package datasource
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookmarks(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal("logs/race1.bookmarks.json", BookmarksFile("logs/race1.csv"))
+
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	var bookmarks Bookmarks
+	bookmarks.Add(start.Add(5*time.Minute), "Late tack on the shift")
+	bookmarks.Add(start, "Good start at the pin")
+	bookmarks.Add(start.Add(2*time.Minute), "Lost height in the chop")
+	require.Equal("Good start at the pin", bookmarks[0].Note)
+	require.Equal("Late tack on the shift", bookmarks[2].Note)
+
+	bookmarks.Remove(1)
+	require.Len(bookmarks, 2)
+
+	var buf bytes.Buffer
+	require.NoError(bookmarks.Save(&buf))
+	loaded, err := LoadBookmarks(&buf)
+	require.NoError(err)
+	require.Len(loaded, 2)
+	require.True(start.Equal(loaded[0].Time))
+	require.Equal("Late tack on the shift", loaded[1].Note)
+
+	_, err = LoadBookmarks(bytes.NewBufferString("{"))
+	require.Error(err)
+}
@@ -0,0 +1,162 @@
+package datasource
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+)
+
+const (
+	// Points slower than this (knots) are ignored when fitting the calibration
+	calibrationMinSpeed = 2.0
+	// Points with an absolute TWA below this are considered to be sailing upwind
+	calibrationUpwindTWA = 90.0
+)
+
+// Calibration is a table of instrument corrections estimated from logged data
+type Calibration struct {
+	// CompassOffset is added to the heading and true wind direction (degrees)
+	CompassOffset float64 `json:"compassOffset"`
+	// AWAOffset is added to the apparent wind angle (degrees)
+	AWAOffset float64 `json:"awaOffset"`
+	// STWFactor multiplies the speed through water. Zero is treated as 1.
+	STWFactor float64 `json:"stwFactor"`
+	// TWDTackError is half of the difference between starboard and port tack true wind
+	// directions. It is subtracted from TWD and TWA on starboard and added on port tack.
+	TWDTackError float64 `json:"twdTackError"`
+}
+
+// EstimateCalibration reads all the points from the provider and fits the compass offset,
+// AWA offset, STW factor and the TWD tack-to-tack error.
+//
+// Compass offset comes from the difference between COG and heading, averaged over both tacks
+// so that leeway cancels out. AWA offset and TWD tack error come from the asymmetry between
+// starboard and port tack when sailing upwind. STW factor is the ratio of SOG to STW over
+// the whole session, which assumes that there is little or no current.
+func EstimateCalibration(provider NavigationDataProvider) (Calibration, error) {
+	var (
+		cogDiff    [2][]float64
+		awa        [2][]float64
+		twd        [2][]float64
+		sumSOG     float64
+		sumSTW     float64
+		tackPoints [2]int
+	)
+
+	for {
+		p, ok := provider.Next()
+		if !ok {
+			break
+		}
+
+		if p.SpeedOverGround < calibrationMinSpeed || p.SpeedThroughWater < calibrationMinSpeed {
+			continue
+		}
+
+		twa := NormalizeAngle(p.TrueWindAngle)
+		if twa == 0 || math.Abs(twa) == 180 {
+			continue
+		}
+		tack := tackIndex(twa)
+		tackPoints[tack]++
+
+		cogDiff[tack] = append(cogDiff[tack], NormalizeAngle(p.CourseOverGround-p.Heading))
+		sumSOG += p.SpeedOverGround
+		sumSTW += p.SpeedThroughWater
+
+		if math.Abs(twa) < calibrationUpwindTWA {
+			awa[tack] = append(awa[tack], math.Abs(NormalizeAngle(p.ApparentWindAngle)))
+			twd[tack] = append(twd[tack], p.TrueWindDirection)
+		}
+	}
+
+	if tackPoints[0] == 0 || tackPoints[1] == 0 {
+		return Calibration{}, errors.New("calibration needs data from both tacks")
+	}
+	if len(twd[0]) == 0 || len(twd[1]) == 0 {
+		return Calibration{}, errors.New("calibration needs upwind data from both tacks")
+	}
+
+	return Calibration{
+		CompassOffset: (MeanAngle(cogDiff[0]) + MeanAngle(cogDiff[1])) / 2,
+		AWAOffset:     -(mean(awa[0]) - mean(awa[1])) / 2,
+		STWFactor:     sumSOG / sumSTW,
+		TWDTackError:  NormalizeAngle(MeanAngle(twd[0])-MeanAngle(twd[1])) / 2,
+	}, nil
+}
+
+// tackIndex returns 0 for starboard tack (wind from the right, positive TWA) and 1 for port
+func tackIndex(twa float64) int {
+	if twa > 0 {
+		return 0
+	}
+	return 1
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// Apply returns a copy of the navigation data point with the corrections applied
+func (c Calibration) Apply(p NavigationDataPoint) NavigationDataPoint {
+	p.Heading = NormalizeDirection(p.Heading + c.CompassOffset)
+	p.TrueWindDirection = NormalizeDirection(p.TrueWindDirection + c.CompassOffset)
+	p.ApparentWindAngle += c.AWAOffset
+
+	if c.STWFactor != 0 {
+		p.SpeedThroughWater *= c.STWFactor
+	}
+
+	twa := NormalizeAngle(p.TrueWindAngle)
+	if twa > 0 {
+		p.TrueWindDirection = NormalizeDirection(p.TrueWindDirection - c.TWDTackError)
+		p.TrueWindAngle -= c.TWDTackError
+	} else if twa < 0 {
+		p.TrueWindDirection = NormalizeDirection(p.TrueWindDirection + c.TWDTackError)
+		p.TrueWindAngle += c.TWDTackError
+	}
+
+	return p
+}
+
+// LoadCalibration reads a JSON encoded calibration table
+func LoadCalibration(reader io.Reader) (Calibration, error) {
+	var c Calibration
+	if err := json.NewDecoder(reader).Decode(&c); err != nil {
+		return Calibration{}, err
+	}
+	return c, nil
+}
+
+// Save writes the calibration table as JSON
+func (c Calibration) Save(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(c)
+}
+
+// CalibratedNavigationDataProvider applies a calibration to every point of the wrapped provider
+type CalibratedNavigationDataProvider struct {
+	provider    NavigationDataProvider
+	calibration Calibration
+}
+
+func NewCalibratedNavigationDataProvider(provider NavigationDataProvider, calibration Calibration) *CalibratedNavigationDataProvider {
+	return &CalibratedNavigationDataProvider{
+		provider:    provider,
+		calibration: calibration,
+	}
+}
+
+func (c *CalibratedNavigationDataProvider) Next() (NavigationDataPoint, bool) {
+	p, ok := c.provider.Next()
+	if !ok {
+		return NavigationDataPoint{}, false
+	}
+	return c.calibration.Apply(p), true
+}
@@ -0,0 +1,92 @@
+package datasource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type sliceProvider struct {
+	points []NavigationDataPoint
+	pos    int
+}
+
+func (s *sliceProvider) Next() (NavigationDataPoint, bool) {
+	if s.pos >= len(s.points) {
+		return NavigationDataPoint{}, false
+	}
+	s.pos++
+	return s.points[s.pos-1], true
+}
+
+// miscalibratedPoint returns a point sailing upwind in a 180 degree true wind, as it would
+// be logged by instruments with the given errors.
+func miscalibratedPoint(twa, compassOffset, awaOffset, stwFactor, tackError float64) NavigationDataPoint {
+	const (
+		twd    = 180.0
+		sog    = 6.0
+		awa    = 30.0
+		leeway = 3.0
+	)
+
+	trueHeading := twd - twa
+	cog := trueHeading - leeway
+	twdError := tackError
+	awaSign := 1.0
+	if twa < 0 {
+		cog = trueHeading + leeway
+		twdError = -tackError
+		awaSign = -1.0
+	}
+
+	return NavigationDataPoint{
+		Heading:           NormalizeDirection(trueHeading - compassOffset),
+		CourseOverGround:  NormalizeDirection(cog),
+		SpeedOverGround:   sog,
+		SpeedThroughWater: sog / stwFactor,
+		TrueWindAngle:     twa + twdError,
+		TrueWindDirection: NormalizeDirection(twd - compassOffset + twdError),
+		ApparentWindAngle: awaSign*awa - awaOffset,
+	}
+}
+
+func TestEstimateCalibration(t *testing.T) {
+	require := require.New(t)
+
+	var points []NavigationDataPoint
+	for i := 0; i < 10; i++ {
+		points = append(points,
+			miscalibratedPoint(42, 4, 2, 1.1, 3),
+			miscalibratedPoint(-42, 4, 2, 1.1, 3),
+		)
+	}
+
+	c, err := EstimateCalibration(&sliceProvider{points: points})
+	require.NoError(err)
+	require.InDelta(4.0, c.CompassOffset, 0.001)
+	require.InDelta(2.0, c.AWAOffset, 0.001)
+	require.InDelta(1.1, c.STWFactor, 0.001)
+	require.InDelta(3.0, c.TWDTackError, 0.001)
+
+	for _, p := range points {
+		corrected := c.Apply(p)
+		require.InDelta(180.0, corrected.TrueWindDirection, 0.001)
+		require.InDelta(30.0, NormalizeAngle(corrected.ApparentWindAngle)*float64(sign(p.TrueWindAngle)), 0.001)
+		require.InDelta(6.0, corrected.SpeedThroughWater, 0.001)
+	}
+}
+
+func TestEstimateCalibrationNeedsBothTacks(t *testing.T) {
+	require := require.New(t)
+
+	points := []NavigationDataPoint{miscalibratedPoint(42, 0, 0, 1, 0)}
+	_, err := EstimateCalibration(&sliceProvider{points: points})
+	require.Error(err)
+}
+
+func sign(v float64) int {
+	if v < 0 {
+		return -1
+	}
+	return 1
+}
@@ -0,0 +1,273 @@
+package datasource
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Rounding sides of the marks, the side of the boat that faces the mark when rounding it
+const (
+	RoundingPort      = "port"
+	RoundingStarboard = "starboard"
+)
+
+// LatLng is a location on the course
+type LatLng struct {
+	Latitude  float64 `json:"lat"`
+	Longitude float64 `json:"lng"`
+}
+
+// Line is a start or finish line between the committee boat and the pin
+type Line struct {
+	Boat LatLng `json:"boat"`
+	Pin  LatLng `json:"pin"`
+}
+
+// Center returns the middle of the line
+func (l Line) Center() LatLng {
+	return LatLng{
+		Latitude:  (l.Boat.Latitude + l.Pin.Latitude) / 2,
+		Longitude: (l.Boat.Longitude + l.Pin.Longitude) / 2,
+	}
+}
+
+// CourseMark is a mark to round, or a gate when the second mark is set
+type CourseMark struct {
+	Name string `json:"name"`
+	LatLng
+	// Rounding is RoundingPort or RoundingStarboard, ignored for gates
+	Rounding string  `json:"rounding,omitempty"`
+	Gate     *LatLng `json:"gate,omitempty"`
+}
+
+// IsGate returns true if the mark is a gate of two marks
+func (m CourseMark) IsGate() bool {
+	return m.Gate != nil
+}
+
+// Target returns the location that the boats sail to, the middle of the gate for gates
+func (m CourseMark) Target() LatLng {
+	if m.Gate == nil {
+		return m.LatLng
+	}
+	return Line{Boat: m.LatLng, Pin: *m.Gate}.Center()
+}
+
+// Course is the race course, the marks are in the order they are rounded
+type Course struct {
+	Name  string       `json:"name"`
+	Start Line         `json:"start"`
+	Marks []CourseMark `json:"marks"`
+	// Finish is the finish line, the start line is used if it's not set
+	Finish *Line `json:"finish,omitempty"`
+}
+
+// CourseLeg is a leg of the course from the previous mark to the next one
+type CourseLeg struct {
+	Name string
+	From LatLng
+	To   LatLng
+	// Distance is the straight line length of the leg in nautical miles
+	Distance float64
+}
+
+// FinishLine returns the finish line
+func (c *Course) FinishLine() Line {
+	if c.Finish == nil {
+		return c.Start
+	}
+	return *c.Finish
+}
+
+// Legs returns the legs from the start line around the marks to the finish line
+func (c *Course) Legs() []CourseLeg {
+	from := c.Start.Center()
+	var legs []CourseLeg
+	addLeg := func(name string, to LatLng) {
+		legs = append(legs, CourseLeg{
+			Name:     name,
+			From:     from,
+			To:       to,
+			Distance: Distance(from.Latitude, from.Longitude, to.Latitude, to.Longitude),
+		})
+		from = to
+	}
+
+	for _, m := range c.Marks {
+		addLeg(m.Name, m.Target())
+	}
+	addLeg("Finish", c.FinishLine().Center())
+
+	return legs
+}
+
+// Validate checks that the course has the lines and the marks are well defined
+func (c *Course) Validate() error {
+	if c.Start.Boat == (LatLng{}) || c.Start.Pin == (LatLng{}) {
+		return errors.New("course has no start line")
+	}
+	if len(c.Marks) == 0 {
+		return errors.New("course has no marks")
+	}
+	for i, m := range c.Marks {
+		if !m.IsGate() && m.Rounding != RoundingPort && m.Rounding != RoundingStarboard {
+			return fmt.Errorf("mark %d %q has invalid rounding %q", i+1, m.Name, m.Rounding)
+		}
+	}
+	return nil
+}
+
+// LoadCourse reads a course from a JSON course file or GPX waypoints. In GPX the waypoint
+// type tells what the waypoint is: start-boat, start-pin, finish-boat, finish-pin,
+// mark-port, mark-starboard or gate. Consecutive gate waypoints make up one gate.
+func LoadCourse(reader io.Reader) (*Course, error) {
+	buffered := bufio.NewReader(reader)
+	first, err := firstNonSpace(buffered)
+	if err != nil {
+		return nil, err
+	}
+
+	var course *Course
+	if first == '<' {
+		course, err = loadGPXCourse(buffered)
+	} else {
+		course = &Course{}
+		err = json.NewDecoder(buffered).Decode(course)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range course.Marks {
+		if course.Marks[i].Name == "" {
+			course.Marks[i].Name = fmt.Sprintf("Mark %d", i+1)
+		}
+	}
+
+	if err := course.Validate(); err != nil {
+		return nil, err
+	}
+	return course, nil
+}
+
+type gpxWaypoints struct {
+	Name      string `xml:"metadata>name"`
+	Waypoints []struct {
+		Latitude  float64 `xml:"lat,attr"`
+		Longitude float64 `xml:"lon,attr"`
+		Name      string  `xml:"name"`
+		Type      string  `xml:"type"`
+	} `xml:"wpt"`
+}
+
+func loadGPXCourse(reader io.Reader) (*Course, error) {
+	var gpx gpxWaypoints
+	if err := xml.NewDecoder(reader).Decode(&gpx); err != nil {
+		return nil, err
+	}
+
+	course := &Course{Name: gpx.Name}
+	gateOpen := false
+	for _, wpt := range gpx.Waypoints {
+		location := LatLng{Latitude: wpt.Latitude, Longitude: wpt.Longitude}
+		wptType := strings.ToLower(strings.TrimSpace(wpt.Type))
+		if gateOpen && wptType != "gate" {
+			return nil, fmt.Errorf("gate %q has only one mark", course.Marks[len(course.Marks)-1].Name)
+		}
+
+		switch wptType {
+		case "start-boat":
+			course.Start.Boat = location
+		case "start-pin":
+			course.Start.Pin = location
+		case "finish-boat":
+			if course.Finish == nil {
+				course.Finish = &Line{}
+			}
+			course.Finish.Boat = location
+		case "finish-pin":
+			if course.Finish == nil {
+				course.Finish = &Line{}
+			}
+			course.Finish.Pin = location
+		case "mark-port":
+			course.Marks = append(course.Marks, CourseMark{Name: wpt.Name, LatLng: location, Rounding: RoundingPort})
+		case "mark-starboard":
+			course.Marks = append(course.Marks, CourseMark{Name: wpt.Name, LatLng: location, Rounding: RoundingStarboard})
+		case "gate":
+			if gateOpen {
+				course.Marks[len(course.Marks)-1].Gate = &location
+			} else {
+				course.Marks = append(course.Marks, CourseMark{Name: wpt.Name, LatLng: location})
+			}
+			gateOpen = !gateOpen
+		default:
+			return nil, fmt.Errorf("waypoint %q has unknown type %q", wpt.Name, wpt.Type)
+		}
+	}
+	if gateOpen {
+		return nil, fmt.Errorf("gate %q has only one mark", course.Marks[len(course.Marks)-1].Name)
+	}
+
+	return course, nil
+}
+
+// CourseProgress returns the index of the leg of the course that the boat is sailing at each
+// point. A mark is passed when the boat comes within the rounding radius in metres of it, or of
+// the line between the marks of a gate. The boat stays on the last leg to the finish.
+func CourseProgress(points []NavigationDataPoint, course *Course, roundingRadius float64) []int {
+	legs := make([]int, len(points))
+	leg := 0
+	for i, p := range points {
+		if leg < len(course.Marks) {
+			m := course.Marks[leg]
+			distance := Distance(p.Latitude, p.Longitude, m.Latitude, m.Longitude) * MetersPerNauticalMile
+			if m.IsGate() {
+				distance = distanceToSegment(p.LatLng(), m.LatLng, *m.Gate)
+			}
+			if distance <= roundingRadius {
+				leg++
+			}
+		}
+		legs[i] = leg
+	}
+	return legs
+}
+
+// LatLng returns the location of the point
+func (p NavigationDataPoint) LatLng() LatLng {
+	return LatLng{Latitude: p.Latitude, Longitude: p.Longitude}
+}
+
+// distanceToSegment returns the distance in metres from the point to the line segment
+func distanceToSegment(p, a, b LatLng) float64 {
+	projection := NewLocalProjection(p.Latitude, p.Longitude)
+	ax, ay := projection.ToMeters(a.Latitude, a.Longitude)
+	bx, by := projection.ToMeters(b.Latitude, b.Longitude)
+	dx, dy := bx-ax, by-ay
+
+	t := 0.0
+	if lengthSquared := dx*dx + dy*dy; lengthSquared > 0 {
+		t = math.Max(0, math.Min(1, -(ax*dx+ay*dy)/lengthSquared))
+	}
+	return math.Hypot(ax+t*dx, ay+t*dy)
+}
+
+// firstNonSpace returns the first byte that isn't white space without consuming it
+func firstNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b)) {
+			return b, reader.UnreadByte()
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package datasource
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testCourseJSON = `{
+  "name": "Windward-leeward",
+  "start": {"boat": {"lat": 59.4500, "lng": 24.7520}, "pin": {"lat": 59.4500, "lng": 24.7480}},
+  "marks": [
+    {"name": "Windward", "lat": 59.4667, "lng": 24.7500, "rounding": "port"},
+    {"name": "Leeward gate", "lat": 59.4520, "lng": 24.7490, "gate": {"lat": 59.4520, "lng": 24.7510}}
+  ],
+  "finish": {"boat": {"lat": 59.4667, "lng": 24.7510}, "pin": {"lat": 59.4667, "lng": 24.7490}}
+}`
+
+const testCourseGPX = `<?xml version="1.0"?>
+<gpx version="1.1" creator="test">
+  <metadata><name>Windward-leeward</name></metadata>
+  <wpt lat="59.4500" lon="24.7520"><name>Committee</name><type>start-boat</type></wpt>
+  <wpt lat="59.4500" lon="24.7480"><name>Pin</name><type>start-pin</type></wpt>
+  <wpt lat="59.4667" lon="24.7500"><name>Windward</name><type>mark-port</type></wpt>
+  <wpt lat="59.4520" lon="24.7490"><name>Leeward gate</name><type>gate</type></wpt>
+  <wpt lat="59.4520" lon="24.7510"><name>Leeward gate</name><type>gate</type></wpt>
+  <wpt lat="59.4667" lon="24.7510"><name>Finish boat</name><type>finish-boat</type></wpt>
+  <wpt lat="59.4667" lon="24.7490"><name>Finish pin</name><type>finish-pin</type></wpt>
+</gpx>`
+
+func TestLoadCourse(t *testing.T) {
+	require := require.New(t)
+
+	for _, data := range []string{testCourseJSON, testCourseGPX} {
+		course, err := LoadCourse(strings.NewReader(data))
+		require.NoError(err)
+
+		require.Equal("Windward-leeward", course.Name)
+		require.InDelta(24.7480, course.Start.Pin.Longitude, 0.00001)
+		require.Len(course.Marks, 2)
+		require.Equal("Windward", course.Marks[0].Name)
+		require.Equal(RoundingPort, course.Marks[0].Rounding)
+		require.False(course.Marks[0].IsGate())
+		require.True(course.Marks[1].IsGate())
+		require.InDelta(24.7500, course.Marks[1].Target().Longitude, 0.00001)
+		require.NotNil(course.Finish)
+
+		legs := course.Legs()
+		require.Len(legs, 3)
+		require.Equal("Windward", legs[0].Name)
+		require.InDelta(1.0, legs[0].Distance, 0.01)
+		require.InDelta(0.88, legs[1].Distance, 0.01)
+		require.Equal("Finish", legs[2].Name)
+		require.InDelta(0.88, legs[2].Distance, 0.01)
+	}
+}
+
+func TestLoadCourseErrors(t *testing.T) {
+	require := require.New(t)
+
+	_, err := LoadCourse(strings.NewReader(`{"marks": [{"lat": 59.4667, "lng": 24.75, "rounding": "port"}]}`))
+	require.ErrorContains(err, "start line")
+
+	_, err = LoadCourse(strings.NewReader(strings.Replace(testCourseJSON, `"port"`, `"left"`, 1)))
+	require.ErrorContains(err, "invalid rounding")
+
+	_, err = LoadCourse(strings.NewReader(strings.Replace(testCourseGPX,
+		`<wpt lat="59.4520" lon="24.7510"><name>Leeward gate</name><type>gate</type></wpt>`, "", 1)))
+	require.ErrorContains(err, "only one mark")
+}
+
+func TestCourseProgress(t *testing.T) {
+	require := require.New(t)
+
+	course, err := LoadCourse(strings.NewReader(testCourseJSON))
+	require.NoError(err)
+
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	locations := []LatLng{
+		{59.4500, 24.7500},
+		{59.4600, 24.7500},
+		// Rounding the windward mark
+		{59.4668, 24.7502},
+		{59.4600, 24.7500},
+		// Passing through the gate
+		{59.4520, 24.7500},
+		{59.4600, 24.7500},
+	}
+	points := make([]NavigationDataPoint, len(locations))
+	for i, l := range locations {
+		points[i] = NavigationDataPoint{Timestamp: start.Add(time.Duration(i) * time.Minute), Latitude: l.Latitude, Longitude: l.Longitude}
+	}
+
+	require.Equal([]int{0, 0, 1, 1, 2, 2}, CourseProgress(points, course, 50))
+}
@@ -24,18 +24,48 @@ type NavigationDataPoint struct {
 	SpeedOverGround    float64
 	ApparentWindSpeed  float64
 	ApparentWindAngle  float64
+	// Depth below the transducer in meters
+	Depth float64
+	// WaterTemperature in degrees Celsius
+	WaterTemperature float64
+	// HasWaterTemperature is set when the water temperature was logged, 0 is a valid temperature
+	HasWaterTemperature bool
+	// RateOfTurn in degrees per minute, positive when turning to starboard
+	RateOfTurn float64
+	// Pitch and Roll in degrees, roll is the heel of the boat
+	Pitch float64
+	Roll  float64
+	// VelocityMadeGood to wind in knots
+	VelocityMadeGood float64
+	// Extra holds the other numeric channels that the logger recorded, by channel name
+	Extra map[string]float64
+}
+
+// kelvinOffset converts between the logged water temperature in Kelvin and Celsius
+const kelvinOffset = 273.15
+
+// mappedColumns are the CSV columns that are parsed into the NavigationDataPoint fields,
+// all the other numeric columns end up in Extra
+var mappedColumns = map[string]bool{
+	"time": true, "hdg": true, "twa": true, "tws": true, "twd": true, "aws": true, "awa": true,
+	"lat": true, "lng": true, "cum_dist": true, "cog": true, "sog": true, "stw": true,
+	"dpt": true, "mtw": true, "rot": true, "pitch": true, "roll": true, "vmg": true,
 }
 
 type NavigationDataProvider interface {
 	Next() (NavigationDataPoint, bool)
 }
 
+// ReplayNavigationDataProvider provides the navigation data points from a CSV log. The whole
+// log is parsed when the provider is created, so it's seekable.
 type ReplayNavigationDataProvider struct {
-	startTime *time.Time
-	endTime   *time.Time
-	fieldMap  map[string]int
-	records   [][]string
-	pos       int
+	PointsNavigationDataProvider
+	startTime    *time.Time
+	endTime      *time.Time
+	fieldMap     map[string]int
+	extraColumns []string
+	records      [][]string
+	recordPos    int
 }
 
 func NewReplayNavigationDataProvider(reader io.Reader, startTime, endTime *time.Time) (*ReplayNavigationDataProvider, error) {
@@ -49,16 +79,75 @@ func NewReplayNavigationDataProvider(reader io.Reader, startTime, endTime *time.
 	}
 
 	fieldMap := make(map[string]int)
+	var extraColumns []string
 	for pos, key := range records[0] {
 		fieldMap[key] = pos
+		if !mappedColumns[key] {
+			extraColumns = append(extraColumns, key)
+		}
+	}
+
+	r := &ReplayNavigationDataProvider{
+		startTime:    startTime,
+		endTime:      endTime,
+		fieldMap:     fieldMap,
+		extraColumns: extraColumns,
+		records:      records[1:],
+	}
+
+	for {
+		d, ok := r.nextRecord()
+		if !ok {
+			break
+		}
+		r.points = append(r.points, d)
+	}
+
+	return r, nil
+}
+
+// ProvidedFields returns the fields that have a value in any record of the log
+func (r *ReplayNavigationDataProvider) ProvidedFields() []Field {
+	logged := func(columns ...string) bool {
+		for _, column := range columns {
+			pos, ok := r.fieldMap[column]
+			if !ok {
+				return false
+			}
+			found := false
+			for _, record := range r.records {
+				if pos < len(record) && record[pos] != "" {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
 	}
 
-	return &ReplayNavigationDataProvider{
-		startTime: startTime,
-		endTime:   endTime,
-		fieldMap:  fieldMap,
-		records:   records[1:],
-	}, nil
+	fields := make(fieldSet)
+	for _, f := range AllFields {
+		switch f {
+		case FieldPosition:
+			if logged("lat", "lng") {
+				fields.add(f)
+			}
+		case FieldExtra:
+			for _, column := range r.extraColumns {
+				if logged(column) {
+					fields.add(f)
+				}
+			}
+		default:
+			if logged(f.String()) {
+				fields.add(f)
+			}
+		}
+	}
+	return fields.list()
 }
 
 func (r *ReplayNavigationDataProvider) assignFieldValue(record []string, key string, target *float64) bool {
@@ -79,6 +168,35 @@ func (r *ReplayNavigationDataProvider) assignFieldValue(record []string, key str
 	return true
 }
 
+// assignOptionalFieldValue is like assignFieldValue but stays quiet about missing or empty fields
+func (r *ReplayNavigationDataProvider) assignOptionalFieldValue(record []string, key string, target *float64) bool {
+	fieldPos, ok := r.fieldMap[key]
+	if !ok || fieldPos >= len(record) || record[fieldPos] == "" {
+		return false
+	}
+	return r.assignFieldValue(record, key, target)
+}
+
+// extraFields returns the numeric values of the columns that are not mapped to fields
+func (r *ReplayNavigationDataProvider) extraFields(record []string) map[string]float64 {
+	var extra map[string]float64
+	for _, key := range r.extraColumns {
+		fieldPos := r.fieldMap[key]
+		if fieldPos >= len(record) {
+			continue
+		}
+		val, err := strconv.ParseFloat(record[fieldPos], 64)
+		if err != nil {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]float64)
+		}
+		extra[key] = val
+	}
+	return extra
+}
+
 func (r *ReplayNavigationDataProvider) isTimeInRange(timestamp time.Time) bool {
 	if r.startTime != nil && timestamp.Before(*r.startTime) {
 		return false
@@ -89,15 +207,16 @@ func (r *ReplayNavigationDataProvider) isTimeInRange(timestamp time.Time) bool {
 	return true
 }
 
-func (r *ReplayNavigationDataProvider) Next() (NavigationDataPoint, bool) {
+// nextRecord parses the next CSV record that is in the requested time range
+func (r *ReplayNavigationDataProvider) nextRecord() (NavigationDataPoint, bool) {
 	timeFieldPos, ok := r.fieldMap["time"]
 	if !ok {
 		return NavigationDataPoint{}, false
 	}
 
-	for r.pos < len(r.records) {
-		record := r.records[r.pos]
-		r.pos++
+	for r.recordPos < len(r.records) {
+		record := r.records[r.recordPos]
+		r.recordPos++
 
 		if timeFieldPos >= len(record) {
 			log.Printf("timeFieldPos: %d, record: %v", timeFieldPos, record)
@@ -138,6 +257,18 @@ func (r *ReplayNavigationDataProvider) Next() (NavigationDataPoint, bool) {
 		// Ignore errors from sometimes missing stw field
 		r.assignFieldValue(record, "stw", &result.SpeedThroughWater)
 
+		// Channels that not all loggers have
+		r.assignOptionalFieldValue(record, "dpt", &result.Depth)
+		if r.assignOptionalFieldValue(record, "mtw", &result.WaterTemperature) {
+			result.WaterTemperature -= kelvinOffset
+			result.HasWaterTemperature = true
+		}
+		r.assignOptionalFieldValue(record, "rot", &result.RateOfTurn)
+		r.assignOptionalFieldValue(record, "pitch", &result.Pitch)
+		r.assignOptionalFieldValue(record, "roll", &result.Roll)
+		r.assignOptionalFieldValue(record, "vmg", &result.VelocityMadeGood)
+		result.Extra = r.extraFields(record)
+
 		if ok {
 			return result, true
 		}
@@ -146,27 +277,6 @@ func (r *ReplayNavigationDataProvider) Next() (NavigationDataPoint, bool) {
 	return NavigationDataPoint{}, false
 }
 
-// GetAllPoints returns all navigation data points in the replay data
-func (r *ReplayNavigationDataProvider) GetAllPoints() []NavigationDataPoint {
-	var points []NavigationDataPoint
-	// Store current position so we can restore it
-	currentPos := r.pos
-	// Reset position to start
-	r.pos = 0
-
-	for {
-		d, ok := r.Next()
-		if !ok {
-			break
-		}
-		points = append(points, d)
-	}
-
-	// Restore original position
-	r.pos = currentPos
-	return points
-}
-
 // GetBounds returns the minimum and maximum latitude and longitude values from a slice of navigation points
 func GetBounds(points []NavigationDataPoint) (minLat, maxLat, minLng, maxLng float64) {
 	if len(points) == 0 {
@@ -206,7 +316,85 @@ func MedianWindDirection(points []NavigationDataPoint) float64 {
 	n := len(windDirections)
 	medianWind := windDirections[n/2]
 	if len(windDirections)%2 == 0 {
-		medianWind = (windDirections[n/2] + windDirections[n/2+1]) / 2
+		medianWind = (windDirections[n/2-1] + windDirections[n/2]) / 2
 	}
 	return medianWind
 }
+
+// csvColumns are the columns written by WriteCSV, followed by the extra channels
+var csvColumns = []string{
+	"time", "dpt", "mtw", "awa", "aws", "cog", "hdg", "rot", "sog", "stw", "pitch", "roll",
+	"lng", "lat", "tws", "twa", "twd", "vmg", "cum_dist",
+}
+
+// WriteCSV writes the navigation data points in the same CSV format that is read by
+// ReplayNavigationDataProvider
+func WriteCSV(writer io.Writer, points []NavigationDataPoint) error {
+	extraColumns := ExtraChannels(points)
+
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.Write(append(csvColumns, extraColumns...)); err != nil {
+		return err
+	}
+
+	formatFloat := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	for _, p := range points {
+		waterTemperature := ""
+		if p.HasWaterTemperature {
+			waterTemperature = formatFloat(p.WaterTemperature + kelvinOffset)
+		}
+		record := []string{
+			p.Timestamp.Format(time.RFC3339Nano),
+			formatFloat(p.Depth),
+			waterTemperature,
+			formatFloat(p.ApparentWindAngle),
+			formatFloat(p.ApparentWindSpeed),
+			formatFloat(p.CourseOverGround),
+			formatFloat(p.Heading),
+			formatFloat(p.RateOfTurn),
+			formatFloat(p.SpeedOverGround),
+			formatFloat(p.SpeedThroughWater),
+			formatFloat(p.Pitch),
+			formatFloat(p.Roll),
+			formatFloat(p.Longitude),
+			formatFloat(p.Latitude),
+			formatFloat(p.TrueWindSpeed),
+			formatFloat(p.TrueWindAngle),
+			formatFloat(p.TrueWindDirection),
+			formatFloat(p.VelocityMadeGood),
+			formatFloat(p.CumulativeDistance),
+		}
+		for _, key := range extraColumns {
+			value := ""
+			if v, ok := p.Extra[key]; ok {
+				value = formatFloat(v)
+			}
+			record = append(record, value)
+		}
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// ExtraChannels returns the sorted names of all the extra channels found in the points
+func ExtraChannels(points []NavigationDataPoint) []string {
+	seen := make(map[string]bool)
+	var channels []string
+	for _, p := range points {
+		for key := range p.Extra {
+			if !seen[key] {
+				seen[key] = true
+				channels = append(channels, key)
+			}
+		}
+	}
+	sort.Strings(channels)
+	return channels
+}
@@ -32,7 +32,63 @@ func TestReplayNavigationDataProvider(t *testing.T) {
 	require.Equal(float64(24.798672), d.Longitude)
 	require.Equal(float64(55), d.CumulativeDistance)
 	require.Equal(float64(87.18), d.CourseOverGround)
+	require.Equal(float64(22.5), d.Depth)
+	require.InDelta(float64(15.5), d.WaterTemperature, 0.001)
+	require.InDelta(float64(1.6), d.RateOfTurn, 0.01)
+	require.InDelta(float64(-3.1), d.Pitch, 0.01)
+	require.InDelta(float64(-20.6), d.Roll, 0.01)
+	require.InDelta(float64(4.08), d.VelocityMadeGood, 0.01)
+	require.Len(d.Extra, 2)
+	require.InDelta(float64(-101.8), d.Extra["yaw"], 0.01)
+	require.InDelta(float64(0.0016), d.Extra["dist"], 0.0001)
 
 	d, ok = ds.Next()
 	require.False(ok)
 }
+
+func TestWriteCSV(t *testing.T) {
+	require := require.New(t)
+
+	points := []NavigationDataPoint{{
+		Timestamp:           time.Date(2024, 9, 11, 17, 27, 52, 0, time.UTC),
+		Heading:             78,
+		Latitude:            59.488683,
+		Longitude:           24.798672,
+		WaterTemperature:    15.5,
+		HasWaterTemperature: true,
+		Roll:                -20.5,
+		SpeedThroughWater:   5.5,
+		Extra:               map[string]float64{"yaw": -101.75},
+	}, {
+		// No temperature was logged, the column is left empty
+		Timestamp: time.Date(2024, 9, 11, 17, 27, 53, 0, time.UTC),
+		Heading:   80,
+		Latitude:  59.488683,
+		Longitude: 24.798722,
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(WriteCSV(&buf, points))
+	require.NotContains(buf.String(), "273.15")
+
+	ds, err := NewReplayNavigationDataProvider(&buf, nil, nil)
+	require.NoError(err)
+	require.Equal(points, ds.GetAllPoints())
+}
+
+func TestMedianWindDirection(t *testing.T) {
+	require := require.New(t)
+
+	points := func(directions ...float64) []NavigationDataPoint {
+		var result []NavigationDataPoint
+		for _, d := range directions {
+			result = append(result, NavigationDataPoint{TrueWindDirection: d})
+		}
+		return result
+	}
+
+	require.Equal(200.0, MedianWindDirection(points(200)))
+	require.Equal(195.0, MedianWindDirection(points(200, 190)))
+	require.Equal(200.0, MedianWindDirection(points(210, 190, 200)))
+	require.Equal(205.0, MedianWindDirection(points(230, 190, 200, 210)))
+}
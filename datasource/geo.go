@@ -0,0 +1,66 @@
+package datasource
+
+import "math"
+
+const (
+	// EarthRadiusNm is the mean radius of the earth in nautical miles
+	EarthRadiusNm = 3440.065
+	// MetersPerNauticalMile is the length of a nautical mile
+	MetersPerNauticalMile = 1852.0
+	// KnotsPerMeterPerSecond converts speeds from m/s to knots
+	KnotsPerMeterPerSecond = 3600 / MetersPerNauticalMile
+)
+
+// Distance returns the great circle distance between two points in nautical miles
+func Distance(lat1, lng1, lat2, lng2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	dPhi := (lat2 - lat1) * math.Pi / 180
+	dLambda := (lng2 - lng1) * math.Pi / 180
+
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+
+	return 2 * EarthRadiusNm * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// Bearing returns the initial true bearing in degrees from the first point to the second
+func Bearing(lat1, lng1, lat2, lng2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	dLambda := (lng2 - lng1) * math.Pi / 180
+
+	y := math.Sin(dLambda) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dLambda)
+
+	return NormalizeDirection(math.Atan2(y, x) * 180 / math.Pi)
+}
+
+// metersPerDegree is the length of a degree of latitude, consistent with Distance
+const metersPerDegree = EarthRadiusNm * MetersPerNauticalMile * math.Pi / 180
+
+// LocalProjection projects locations to a plane that touches the earth at the origin, with
+// x pointing east and y pointing north in metres. It is accurate over a race course.
+type LocalProjection struct {
+	OriginLatitude  float64
+	OriginLongitude float64
+}
+
+// NewLocalProjection returns a projection anchored at the origin
+func NewLocalProjection(originLat, originLng float64) LocalProjection {
+	return LocalProjection{OriginLatitude: originLat, OriginLongitude: originLng}
+}
+
+// ToMeters returns the distances east and north of the origin in metres
+func (lp LocalProjection) ToMeters(lat, lng float64) (east, north float64) {
+	east = NormalizeAngle(lng-lp.OriginLongitude) * metersPerDegree * math.Cos(lp.OriginLatitude*math.Pi/180)
+	north = (lat - lp.OriginLatitude) * metersPerDegree
+	return east, north
+}
+
+// ToLatLng converts the metres east and north of the origin back to latitude and longitude
+func (lp LocalProjection) ToLatLng(east, north float64) (lat, lng float64) {
+	lat = lp.OriginLatitude + north/metersPerDegree
+	lng = lp.OriginLongitude + east/(metersPerDegree*math.Cos(lp.OriginLatitude*math.Pi/180))
+	return lat, lng
+}
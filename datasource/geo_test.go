@@ -0,0 +1,29 @@
+package datasource
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalProjection(t *testing.T) {
+	require := require.New(t)
+
+	projection := NewLocalProjection(59.45, 24.75)
+
+	east, north := projection.ToMeters(59.45, 24.75)
+	require.InDelta(0, east, 0.001)
+	require.InDelta(0, north, 0.001)
+
+	// The projected distances agree with the great circle distance on a course sized area
+	lat, lng := 59.46, 24.77
+	east, north = projection.ToMeters(lat, lng)
+	require.Greater(east, 0.0)
+	require.Greater(north, 0.0)
+	require.InDelta(Distance(59.45, 24.75, lat, lng)*MetersPerNauticalMile, math.Hypot(east, north), 1)
+
+	lat2, lng2 := projection.ToLatLng(east, north)
+	require.InDelta(lat, lat2, 1e-9)
+	require.InDelta(lng, lng2, 1e-9)
+}
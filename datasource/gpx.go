@@ -0,0 +1,83 @@
+package datasource
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"time"
+)
+
+type gpxFile struct {
+	Tracks []struct {
+		Segments []struct {
+			Points []gpxPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+type gpxPoint struct {
+	Latitude  float64   `xml:"lat,attr"`
+	Longitude float64   `xml:"lon,attr"`
+	Time      time.Time `xml:"time"`
+	Course    *float64  `xml:"course"`
+	Speed     *float64  `xml:"speed"`
+}
+
+// GPXNavigationDataProvider provides the track points of a GPX file. GPX only has positions,
+// so course and speed over ground are calculated from consecutive points unless the file
+// has the GPX 1.0 course and speed elements.
+type GPXNavigationDataProvider struct {
+	PointsNavigationDataProvider
+}
+
+func NewGPXNavigationDataProvider(reader io.Reader) (*GPXNavigationDataProvider, error) {
+	var gpx gpxFile
+	if err := xml.NewDecoder(reader).Decode(&gpx); err != nil {
+		return nil, err
+	}
+
+	var points []NavigationDataPoint
+	for _, track := range gpx.Tracks {
+		for _, segment := range track.Segments {
+			for _, tp := range segment.Points {
+				if tp.Time.IsZero() {
+					continue
+				}
+
+				p := NavigationDataPoint{
+					Timestamp: tp.Time,
+					Latitude:  tp.Latitude,
+					Longitude: tp.Longitude,
+				}
+
+				if len(points) > 0 {
+					prev := points[len(points)-1]
+					distance := Distance(prev.Latitude, prev.Longitude, p.Latitude, p.Longitude)
+					p.CumulativeDistance = prev.CumulativeDistance + distance
+					p.CourseOverGround = prev.CourseOverGround
+					if distance > 0 {
+						p.CourseOverGround = Bearing(prev.Latitude, prev.Longitude, p.Latitude, p.Longitude)
+					}
+					if elapsed := p.Timestamp.Sub(prev.Timestamp).Hours(); elapsed > 0 {
+						p.SpeedOverGround = distance / elapsed
+					}
+				}
+
+				if tp.Course != nil {
+					p.CourseOverGround = *tp.Course
+				}
+				if tp.Speed != nil {
+					p.SpeedOverGround = *tp.Speed * KnotsPerMeterPerSecond
+				}
+
+				points = append(points, p)
+			}
+		}
+	}
+
+	if len(points) == 0 {
+		return nil, errors.New("no track points with timestamps")
+	}
+
+	return &GPXNavigationDataProvider{PointsNavigationDataProvider{points: points}}, nil
+}
@@ -0,0 +1,83 @@
+package datasource
+
+import "math"
+
+// Ends of the start line
+const (
+	FavouredBoat   = "boat"
+	FavouredPin    = "pin"
+	FavouredSquare = "square"
+)
+
+// squareLineBias is the bias in degrees below which neither end of the line is favoured
+const squareLineBias = 1.0
+
+// LineBias returns how many degrees the line is turned from square to the wind, given the bearing
+// of the line from the pin to the committee boat and the true wind direction. The bias is positive
+// when the committee boat end is upwind and favoured.
+func LineBias(lineBearing, twd float64) float64 {
+	return NormalizeAngle(twd + 90 - lineBearing)
+}
+
+// BiasDistance returns how much further upwind the favoured end of a line of the length is,
+// positive for the committee boat end
+func BiasDistance(length, bias float64) float64 {
+	return length * math.Sin(bias*math.Pi/180)
+}
+
+// FavouredEnd returns the end of the line that is upwind, or that the line is square
+func FavouredEnd(bias float64) string {
+	switch {
+	case bias >= squareLineBias:
+		return FavouredBoat
+	case bias <= -squareLineBias:
+		return FavouredPin
+	}
+	return FavouredSquare
+}
+
+// Bearing returns the true bearing of the line from the pin to the committee boat
+func (l Line) Bearing() float64 {
+	return Bearing(l.Pin.Latitude, l.Pin.Longitude, l.Boat.Latitude, l.Boat.Longitude)
+}
+
+// Length returns the length of the line in metres
+func (l Line) Length() float64 {
+	return Distance(l.Pin.Latitude, l.Pin.Longitude, l.Boat.Latitude, l.Boat.Longitude) * MetersPerNauticalMile
+}
+
+// Bias returns the bias of the line in the true wind direction in degrees, see LineBias
+func (l Line) Bias(twd float64) float64 {
+	return LineBias(l.Bearing(), twd)
+}
+
+// BiasDistance returns how many metres further upwind the committee boat end is than the pin
+func (l Line) BiasDistance(twd float64) float64 {
+	return BiasDistance(l.Length(), l.Bias(twd))
+}
+
+// LinePings builds the start line from the positions of the boat pinged at its ends
+type LinePings struct {
+	boat *LatLng
+	pin  *LatLng
+}
+
+// PingBoat sets the committee boat end of the line to the location of the point
+func (lp *LinePings) PingBoat(p NavigationDataPoint) {
+	location := p.LatLng()
+	lp.boat = &location
+}
+
+// PingPin sets the pin end of the line to the location of the point
+func (lp *LinePings) PingPin(p NavigationDataPoint) {
+	location := p.LatLng()
+	lp.pin = &location
+}
+
+// Line returns the line, false until both of the ends have been pinged
+func (lp *LinePings) Line() (Line, bool) {
+	if lp.boat == nil || lp.pin == nil {
+		return Line{}, false
+	}
+	return Line{Boat: *lp.boat, Pin: *lp.pin}, true
+}
@@ -0,0 +1,32 @@
+package datasource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineBias(t *testing.T) {
+	require := require.New(t)
+
+	require.InDelta(0, LineBias(90, 0), 0.001)
+	require.InDelta(10, LineBias(80, 0), 0.001)
+	require.InDelta(-15, LineBias(90, 345), 0.001)
+	require.Equal(FavouredBoat, FavouredEnd(10))
+	require.Equal(FavouredPin, FavouredEnd(-15))
+	require.Equal(FavouredSquare, FavouredEnd(0.5))
+	require.InDelta(50, BiasDistance(100, 30), 0.001)
+
+	var pings LinePings
+	_, ok := pings.Line()
+	require.False(ok)
+	pings.PingPin(NavigationDataPoint{Latitude: 59.45, Longitude: 24.748})
+	pings.PingBoat(NavigationDataPoint{Latitude: 59.45, Longitude: 24.752})
+	line, ok := pings.Line()
+	require.True(ok)
+
+	require.InDelta(227, line.Length(), 1)
+	require.InDelta(0, line.Bias(0), 0.01)
+	require.InDelta(10, line.Bias(10), 0.01)
+	require.InDelta(227*0.1736, line.BiasDistance(10), 0.5)
+}
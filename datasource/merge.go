@@ -0,0 +1,373 @@
+package datasource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Field identifies a value of NavigationDataPoint when merging data from multiple sources
+type Field int
+
+const (
+	FieldPosition Field = iota
+	FieldHeading
+	FieldSpeedThroughWater
+	FieldTrueWindAngle
+	FieldTrueWindSpeed
+	FieldTrueWindDirection
+	FieldCumulativeDistance
+	FieldCourseOverGround
+	FieldSpeedOverGround
+	FieldApparentWindSpeed
+	FieldApparentWindAngle
+	FieldDepth
+	FieldWaterTemperature
+	FieldRateOfTurn
+	FieldPitch
+	FieldRoll
+	FieldVelocityMadeGood
+	FieldExtra
+)
+
+// AllFields lists all the fields that can be merged
+var AllFields = []Field{
+	FieldPosition,
+	FieldHeading,
+	FieldSpeedThroughWater,
+	FieldTrueWindAngle,
+	FieldTrueWindSpeed,
+	FieldTrueWindDirection,
+	FieldCumulativeDistance,
+	FieldCourseOverGround,
+	FieldSpeedOverGround,
+	FieldApparentWindSpeed,
+	FieldApparentWindAngle,
+	FieldDepth,
+	FieldWaterTemperature,
+	FieldRateOfTurn,
+	FieldPitch,
+	FieldRoll,
+	FieldVelocityMadeGood,
+	FieldExtra,
+}
+
+// fieldNames uses the same names as the CSV log columns, position stands for both lat and lng
+// and extra for all the channels that are not mapped to a field
+var fieldNames = map[Field]string{
+	FieldPosition:           "position",
+	FieldHeading:            "hdg",
+	FieldSpeedThroughWater:  "stw",
+	FieldTrueWindAngle:      "twa",
+	FieldTrueWindSpeed:      "tws",
+	FieldTrueWindDirection:  "twd",
+	FieldCumulativeDistance: "cum_dist",
+	FieldCourseOverGround:   "cog",
+	FieldSpeedOverGround:    "sog",
+	FieldApparentWindSpeed:  "aws",
+	FieldApparentWindAngle:  "awa",
+	FieldDepth:              "dpt",
+	FieldWaterTemperature:   "mtw",
+	FieldRateOfTurn:         "rot",
+	FieldPitch:              "pitch",
+	FieldRoll:               "roll",
+	FieldVelocityMadeGood:   "vmg",
+	FieldExtra:              "extra",
+}
+
+func (f Field) String() string {
+	return fieldNames[f]
+}
+
+// ParseField returns the field with the given name
+func ParseField(name string) (Field, error) {
+	for f, n := range fieldNames {
+		if n == name {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown field %q", name)
+}
+
+// FieldReporter is implemented by the providers that know which fields their data has values
+// for, so that a source doesn't claim the channels that were never logged
+type FieldReporter interface {
+	// ProvidedFields returns the fields that the points read so far have values for
+	ProvidedFields() []Field
+}
+
+// fieldSet records the fields that a provider has received values for
+type fieldSet map[Field]bool
+
+func (fs fieldSet) add(fields ...Field) {
+	for _, f := range fields {
+		fs[f] = true
+	}
+}
+
+// list returns the fields in the order of AllFields
+func (fs fieldSet) list() []Field {
+	var fields []Field
+	for _, f := range AllFields {
+		if fs[f] {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// hasField returns false if the point can tell that it has no value for the field
+func hasField(f Field, p NavigationDataPoint) bool {
+	switch f {
+	case FieldPosition:
+		// Points from before the first position fix are at 0, 0
+		return p.Latitude != 0 || p.Longitude != 0
+	case FieldWaterTemperature:
+		return p.HasWaterTemperature
+	}
+	return true
+}
+
+// copyField copies the value of the field from one point to another
+func copyField(f Field, from NavigationDataPoint, to *NavigationDataPoint) {
+	switch f {
+	case FieldPosition:
+		to.Latitude = from.Latitude
+		to.Longitude = from.Longitude
+	case FieldHeading:
+		to.Heading = from.Heading
+	case FieldSpeedThroughWater:
+		to.SpeedThroughWater = from.SpeedThroughWater
+	case FieldTrueWindAngle:
+		to.TrueWindAngle = from.TrueWindAngle
+	case FieldTrueWindSpeed:
+		to.TrueWindSpeed = from.TrueWindSpeed
+	case FieldTrueWindDirection:
+		to.TrueWindDirection = from.TrueWindDirection
+	case FieldCumulativeDistance:
+		to.CumulativeDistance = from.CumulativeDistance
+	case FieldCourseOverGround:
+		to.CourseOverGround = from.CourseOverGround
+	case FieldSpeedOverGround:
+		to.SpeedOverGround = from.SpeedOverGround
+	case FieldApparentWindSpeed:
+		to.ApparentWindSpeed = from.ApparentWindSpeed
+	case FieldApparentWindAngle:
+		to.ApparentWindAngle = from.ApparentWindAngle
+	case FieldDepth:
+		to.Depth = from.Depth
+	case FieldWaterTemperature:
+		to.WaterTemperature = from.WaterTemperature
+		to.HasWaterTemperature = from.HasWaterTemperature
+	case FieldRateOfTurn:
+		to.RateOfTurn = from.RateOfTurn
+	case FieldPitch:
+		to.Pitch = from.Pitch
+	case FieldRoll:
+		to.Roll = from.Roll
+	case FieldVelocityMadeGood:
+		to.VelocityMadeGood = from.VelocityMadeGood
+	case FieldExtra:
+		to.Extra = from.Extra
+	}
+}
+
+// MergeSource is one of the inputs of the MergingNavigationDataProvider
+type MergeSource struct {
+	Name     string
+	Provider NavigationDataProvider
+	// ClockOffset is added to the timestamps of this source to align it with the others
+	ClockOffset time.Duration
+	// Fields that are taken from this source. If empty, the fields reported by a FieldReporter
+	// provider, or all fields for the other providers.
+	Fields []Field
+}
+
+type MergeConfig struct {
+	// Sources in the order of precedence, the first source providing a field wins
+	Sources []MergeSource
+	// Precedence overrides the source order for individual fields. It lists the source names
+	// from the most preferred; sources that are not listed, or that don't provide the field
+	// according to their Fields, are not used for the field.
+	Precedence map[Field][]string
+	// Interval between the merged points. If zero, a point is produced for every
+	// timestamp found in any of the sources.
+	Interval time.Duration
+	// MaxAge is how old a source value can be and still be used at a merged timestamp,
+	// defaults to 5 seconds.
+	MaxAge time.Duration
+}
+
+type mergeInput struct {
+	name   string
+	points []NavigationDataPoint
+	fields map[Field]bool
+	pos    int
+}
+
+// latestAt returns the most recent point at or before the timestamp. Timestamps must be
+// requested in increasing order.
+func (m *mergeInput) latestAt(t time.Time) (NavigationDataPoint, bool) {
+	for m.pos+1 < len(m.points) && !m.points[m.pos+1].Timestamp.After(t) {
+		m.pos++
+	}
+	if len(m.points) == 0 || m.points[m.pos].Timestamp.After(t) {
+		return NavigationDataPoint{}, false
+	}
+	return m.points[m.pos], true
+}
+
+// MergingNavigationDataProvider combines several providers into a single time aligned
+// stream of navigation data points. Every field of a merged point is taken from the
+// most preferred source that has a recent enough value for it. Points without a
+// position are dropped.
+type MergingNavigationDataProvider struct {
+	PointsNavigationDataProvider
+}
+
+func NewMergingNavigationDataProvider(config MergeConfig) (*MergingNavigationDataProvider, error) {
+	if len(config.Sources) == 0 {
+		return nil, fmt.Errorf("no sources to merge")
+	}
+
+	maxAge := config.MaxAge
+	if maxAge == 0 {
+		maxAge = 5 * time.Second
+	}
+
+	inputs := make(map[string]*mergeInput)
+	var order []*mergeInput
+	for i, source := range config.Sources {
+		name := source.Name
+		if name == "" {
+			name = fmt.Sprintf("source%d", i+1)
+		}
+		if _, ok := inputs[name]; ok {
+			return nil, fmt.Errorf("duplicate source name %q", name)
+		}
+
+		input := &mergeInput{name: name, fields: make(map[Field]bool)}
+		input.points = ReadAll(source.Provider)
+
+		fields := source.Fields
+		if len(fields) == 0 {
+			fields = AllFields
+			if reporter, ok := source.Provider.(FieldReporter); ok {
+				fields = reporter.ProvidedFields()
+			}
+		}
+		for _, f := range fields {
+			input.fields[f] = true
+		}
+
+		for i := range input.points {
+			input.points[i].Timestamp = input.points[i].Timestamp.Add(source.ClockOffset)
+		}
+		sort.SliceStable(input.points, func(a, b int) bool {
+			return input.points[a].Timestamp.Before(input.points[b].Timestamp)
+		})
+
+		inputs[name] = input
+		order = append(order, input)
+	}
+
+	precedence := make(map[Field][]*mergeInput)
+	for _, f := range AllFields {
+		names, ok := config.Precedence[f]
+		if !ok {
+			for _, input := range order {
+				if input.fields[f] {
+					precedence[f] = append(precedence[f], input)
+				}
+			}
+			continue
+		}
+		for _, name := range names {
+			input, ok := inputs[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown source %q in %v precedence", name, f)
+			}
+			if !input.fields[f] {
+				continue
+			}
+			precedence[f] = append(precedence[f], input)
+		}
+	}
+
+	var points []NavigationDataPoint
+	for _, t := range mergeTimestamps(order, config.Interval) {
+		result := NavigationDataPoint{Timestamp: t}
+		hasPosition := false
+
+		for _, f := range AllFields {
+			for _, input := range precedence[f] {
+				p, ok := input.latestAt(t)
+				if ok && t.Sub(p.Timestamp) <= maxAge && hasField(f, p) {
+					copyField(f, p, &result)
+					hasPosition = hasPosition || f == FieldPosition
+					break
+				}
+			}
+		}
+
+		if hasPosition {
+			points = append(points, result)
+		}
+	}
+
+	return &MergingNavigationDataProvider{PointsNavigationDataProvider{points: points}}, nil
+}
+
+// mergeTimestamps returns the sorted timestamps at which the merged points are produced
+func mergeTimestamps(inputs []*mergeInput, interval time.Duration) []time.Time {
+	var all []time.Time
+	for _, input := range inputs {
+		for _, p := range input.points {
+			all = append(all, p.Timestamp)
+		}
+	}
+	if len(all) == 0 {
+		return nil
+	}
+	sort.Slice(all, func(a, b int) bool { return all[a].Before(all[b]) })
+
+	var result []time.Time
+	if interval > 0 {
+		for t := all[0]; !t.After(all[len(all)-1]); t = t.Add(interval) {
+			result = append(result, t)
+		}
+		return result
+	}
+
+	for i, t := range all {
+		if i == 0 || !t.Equal(all[i-1]) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// ParsePrecedence parses a field precedence specification such as "hdg=nmea,csv;position=gpx"
+func ParsePrecedence(spec string) (map[Field][]string, error) {
+	result := make(map[Field][]string)
+	if spec == "" {
+		return result, nil
+	}
+
+	for _, entry := range strings.Split(spec, ";") {
+		name, sources, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid precedence %q, expecting field=source,...", entry)
+		}
+		f, err := ParseField(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		for _, source := range strings.Split(sources, ",") {
+			result[f] = append(result[f], strings.TrimSpace(source))
+		}
+	}
+
+	return result, nil
+}
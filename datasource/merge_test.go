@@ -0,0 +1,144 @@
+package datasource
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testGPX = `<?xml version="1.0"?>
+<gpx version="1.1">
+  <trk><trkseg>
+    <trkpt lat="59.4880" lon="24.7980"><time>2024-09-11T12:00:00Z</time></trkpt>
+    <trkpt lat="59.4881" lon="24.7980"><time>2024-09-11T12:00:02Z</time></trkpt>
+  </trkseg></trk>
+</gpx>`
+
+func TestMergingNavigationDataProvider(t *testing.T) {
+	require := require.New(t)
+
+	gpx, err := NewGPXNavigationDataProvider(strings.NewReader(testGPX))
+	require.NoError(err)
+
+	// Wind instrument clock is 10 seconds ahead of the GPS
+	start := time.Date(2024, 9, 11, 12, 0, 10, 0, time.UTC)
+	instruments := &sliceProvider{points: []NavigationDataPoint{
+		{Timestamp: start, Heading: 10, TrueWindDirection: 180, Latitude: 1, Longitude: 1},
+		{Timestamp: start.Add(time.Second), Heading: 12, TrueWindDirection: 185, Latitude: 1, Longitude: 1},
+	}}
+
+	merged, err := NewMergingNavigationDataProvider(MergeConfig{
+		Sources: []MergeSource{
+			{
+				Name:        "instruments",
+				Provider:    instruments,
+				ClockOffset: -10 * time.Second,
+				Fields:      []Field{FieldPosition, FieldHeading, FieldTrueWindDirection},
+			},
+			{Name: "gpx", Provider: gpx, Fields: []Field{FieldPosition, FieldCourseOverGround, FieldSpeedOverGround}},
+		},
+		Precedence: map[Field][]string{
+			FieldPosition: {"gpx", "instruments"},
+		},
+		Interval: time.Second,
+	})
+	require.NoError(err)
+
+	var points []NavigationDataPoint
+	for {
+		p, ok := merged.Next()
+		if !ok {
+			break
+		}
+		points = append(points, p)
+	}
+	require.Len(points, 3)
+
+	require.Equal(start.Add(-10*time.Second), points[0].Timestamp)
+	require.Equal(59.488, points[0].Latitude)
+	require.Equal(10.0, points[0].Heading)
+	require.Equal(180.0, points[0].TrueWindDirection)
+
+	require.Equal(59.488, points[1].Latitude)
+	require.Equal(12.0, points[1].Heading)
+
+	require.Equal(59.4881, points[2].Latitude)
+	require.InDelta(0.0, points[2].CourseOverGround, 0.001)
+	require.InDelta(10.8, points[2].SpeedOverGround, 0.1)
+	require.Equal(12.0, points[2].Heading)
+}
+
+func TestMergePrecedenceFields(t *testing.T) {
+	require := require.New(t)
+
+	gpx, err := NewGPXNavigationDataProvider(strings.NewReader(testGPX))
+	require.NoError(err)
+
+	start := time.Date(2024, 9, 11, 12, 0, 0, 0, time.UTC)
+	instruments := &sliceProvider{points: []NavigationDataPoint{
+		{Timestamp: start, Heading: 10},
+		{Timestamp: start.Add(2 * time.Second), Heading: 12},
+	}}
+
+	merged, err := NewMergingNavigationDataProvider(MergeConfig{
+		Sources: []MergeSource{
+			{Name: "gpx", Provider: gpx, Fields: []Field{FieldPosition, FieldCourseOverGround, FieldSpeedOverGround}},
+			{Name: "csv", Provider: instruments, Fields: []Field{FieldHeading}},
+		},
+		// The GPX track has no heading, so the heading comes from the instruments anyway
+		Precedence: map[Field][]string{
+			FieldHeading: {"gpx", "csv"},
+		},
+	})
+	require.NoError(err)
+
+	points := ReadAll(merged)
+	require.Len(points, 2)
+	require.Equal(10.0, points[0].Heading)
+	require.Equal(12.0, points[1].Heading)
+}
+
+func TestMergeProvidedFields(t *testing.T) {
+	require := require.New(t)
+
+	// The instruments have no position, speed or apparent wind, those come from the CSV log
+	instruments := NewNMEANavigationDataProvider(strings.NewReader(testInstrumentsNMEA))
+	csvLog, err := NewReplayNavigationDataProvider(strings.NewReader(
+		"time,hdg,sog,cog,stw,twa,tws,twd,aws,awa,lat,lng,cum_dist,dpt\n"+
+			"2024-09-11T12:00:00Z,90,5,90,5,45,10,135,12,30,59.488,24.798,0,20\n"+
+			"2024-09-11T12:00:02Z,90,5,90,5,45,10,135,12,30,59.489,24.798,0,21\n"), nil, nil)
+	require.NoError(err)
+
+	merged, err := NewMergingNavigationDataProvider(MergeConfig{
+		Sources: []MergeSource{
+			{Name: "nmea", Provider: instruments},
+			{Name: "csv", Provider: csvLog},
+		},
+	})
+	require.NoError(err)
+
+	points := ReadAll(merged)
+	require.Len(points, 3)
+	require.Equal(78.0, points[0].Heading)
+	require.Equal(12.5, points[0].Depth)
+	require.Equal(5.0, points[0].SpeedThroughWater)
+	require.Equal(30.0, points[0].ApparentWindAngle)
+	require.Equal(59.488, points[0].Latitude)
+	require.Equal(80.0, points[2].Heading)
+	require.Equal(59.489, points[2].Latitude)
+	require.False(points[2].HasWaterTemperature)
+}
+
+func TestParsePrecedence(t *testing.T) {
+	require := require.New(t)
+
+	p, err := ParsePrecedence("hdg=nmea,csv; position=gpx")
+	require.NoError(err)
+	require.Equal([]string{"nmea", "csv"}, p[FieldHeading])
+	require.Equal([]string{"gpx"}, p[FieldPosition])
+
+	_, err = ParsePrecedence("foo=nmea")
+	require.Error(err)
+}
@@ -0,0 +1,376 @@
+package datasource
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NMEANavigationDataProvider reads NMEA 0183 sentences and produces a navigation data point for
+// every timestamp. The time comes from the RMC and ZDA sentences, or from the tag blocks of a
+// recorded log, and the values of all the sentences up to the next timestamp are combined into the
+// point. The values of the earlier sentences, including the last position fix, are carried over,
+// so a log of the instruments without a GPS still produces points.
+type NMEANavigationDataProvider struct {
+	scanner     *bufio.Scanner
+	current     NavigationDataPoint
+	pending     bool
+	hasTime     bool
+	hasTWD      bool
+	hasPosition bool
+	fields      fieldSet
+	queue       []NavigationDataPoint
+}
+
+func NewNMEANavigationDataProvider(reader io.Reader) *NMEANavigationDataProvider {
+	return &NMEANavigationDataProvider{
+		scanner: bufio.NewScanner(reader),
+		fields:  make(fieldSet),
+	}
+}
+
+func (n *NMEANavigationDataProvider) Next() (NavigationDataPoint, bool) {
+	for len(n.queue) == 0 {
+		if !n.scanner.Scan() {
+			if err := n.scanner.Err(); err != nil {
+				log.Printf("error reading NMEA data: %v", err)
+			}
+			n.flush()
+			if len(n.queue) == 0 {
+				return NavigationDataPoint{}, false
+			}
+			break
+		}
+
+		sentence, fields, tagTime, err := parseNMEASentence(n.scanner.Text())
+		if err != nil {
+			log.Printf("ignoring NMEA sentence: %v", err)
+			continue
+		}
+
+		timestamp, ok := tagTime, !tagTime.IsZero()
+		switch sentence {
+		case "RMC":
+			if t, valid := nmeaRMCTime(fields); valid && !ok {
+				timestamp, ok = t, true
+			}
+		case "ZDA":
+			if t, valid := nmeaZDATime(fields); valid && !ok {
+				timestamp, ok = t, true
+			}
+		}
+		if ok {
+			if n.pending && !timestamp.Equal(n.current.Timestamp) {
+				n.flush()
+			}
+			n.current.Timestamp = timestamp
+			n.hasTime = true
+		}
+
+		n.applySentence(sentence, fields)
+	}
+
+	p := n.queue[0]
+	n.queue = n.queue[1:]
+	return p, true
+}
+
+// ProvidedFields returns the fields that the sentences read so far had values for
+func (n *NMEANavigationDataProvider) ProvidedFields() []Field {
+	return n.fields.list()
+}
+
+// flush queues the point accumulated so far, once its time is known
+func (n *NMEANavigationDataProvider) flush() {
+	if n.pending && n.hasTime {
+		p := n.current
+		if !n.hasTWD {
+			p.TrueWindDirection = NormalizeDirection(p.Heading + p.TrueWindAngle)
+		}
+		n.queue = append(n.queue, p)
+	}
+	n.pending = false
+}
+
+// applySentence updates the current point with the values of the sentence
+func (n *NMEANavigationDataProvider) applySentence(sentence string, fields []string) {
+	switch sentence {
+	case "RMC":
+		if !n.parseRMC(fields) {
+			return
+		}
+		n.fields.add(FieldPosition, FieldCumulativeDistance, FieldSpeedOverGround, FieldCourseOverGround)
+	case "HDT":
+		n.current.Heading = nmeaFloat(fields, 0, n.current.Heading)
+		n.fields.add(FieldHeading)
+	case "HDG":
+		// Magnetic heading with deviation and variation, east is positive
+		heading := nmeaFloat(fields, 0, n.current.Heading)
+		heading += nmeaSigned(fields, 1, 2, "E")
+		heading += nmeaSigned(fields, 3, 4, "E")
+		n.current.Heading = NormalizeDirection(heading)
+		n.fields.add(FieldHeading)
+	case "VHW":
+		n.current.SpeedThroughWater = nmeaFloat(fields, 4, n.current.SpeedThroughWater)
+		n.fields.add(FieldSpeedThroughWater)
+	case "VTG":
+		n.current.CourseOverGround = nmeaFloat(fields, 0, n.current.CourseOverGround)
+		n.current.SpeedOverGround = nmeaFloat(fields, 4, n.current.SpeedOverGround)
+		n.fields.add(FieldCourseOverGround, FieldSpeedOverGround)
+	case "MWD":
+		n.current.TrueWindDirection = nmeaFloat(fields, 0, n.current.TrueWindDirection)
+		n.current.TrueWindSpeed = nmeaFloat(fields, 4, n.current.TrueWindSpeed)
+		n.hasTWD = true
+		n.fields.add(FieldTrueWindDirection, FieldTrueWindSpeed)
+	case "MWV":
+		n.parseMWV(fields)
+	case "DPT":
+		// Depth below transducer, the offset is for the keel or waterline
+		n.current.Depth = nmeaFloat(fields, 0, n.current.Depth)
+		n.fields.add(FieldDepth)
+	case "DBT":
+		n.current.Depth = nmeaFloat(fields, 2, n.current.Depth)
+		n.fields.add(FieldDepth)
+	case "MTW":
+		n.current.WaterTemperature = nmeaFloat(fields, 0, n.current.WaterTemperature)
+		n.current.HasWaterTemperature = true
+		n.fields.add(FieldWaterTemperature)
+	case "ROT":
+		n.current.RateOfTurn = nmeaFloat(fields, 0, n.current.RateOfTurn)
+		n.fields.add(FieldRateOfTurn)
+	case "VPW":
+		n.current.VelocityMadeGood = nmeaFloat(fields, 0, n.current.VelocityMadeGood)
+		n.fields.add(FieldVelocityMadeGood)
+	case "XDR":
+		n.parseXDR(fields)
+	default:
+		return
+	}
+	n.pending = true
+
+	// The true wind direction is computed from the heading and the true wind angle without MWD
+	if n.fields[FieldHeading] && n.fields[FieldTrueWindAngle] {
+		n.fields.add(FieldTrueWindDirection)
+	}
+}
+
+// nmeaRMCTime returns the time of a RMC sentence, it's given even without a position fix
+func nmeaRMCTime(fields []string) (time.Time, bool) {
+	// hhmmss.ss,A,llll.ll,a,yyyyy.yy,a,x.x,x.x,ddmmyy,...
+	if len(fields) < 9 {
+		return time.Time{}, false
+	}
+	return nmeaTime(fields[8], "020106", fields[0])
+}
+
+// nmeaZDATime returns the time of a ZDA sentence
+func nmeaZDATime(fields []string) (time.Time, bool) {
+	// hhmmss.ss,dd,mm,yyyy,zh,zm
+	if len(fields) < 4 {
+		return time.Time{}, false
+	}
+	return nmeaTime(fields[1]+fields[2]+fields[3], "02012006", fields[0])
+}
+
+// nmeaTime parses the date in the layout and the hhmmss.ss time of day
+func nmeaTime(date, layout, timeOfDay string) (time.Time, bool) {
+	timestamp, err := time.Parse(layout+" 150405", date+" "+timeOfDay[:min(6, len(timeOfDay))])
+	if err != nil {
+		return time.Time{}, false
+	}
+	if frac, err := strconv.ParseFloat("0"+strings.TrimLeft(timeOfDay, "0123456789"), 64); err == nil {
+		timestamp = timestamp.Add(time.Duration(frac * float64(time.Second)))
+	}
+	return timestamp, true
+}
+
+// parseRMC updates the current position from a RMC sentence, returns false if there's no valid fix
+func (n *NMEANavigationDataProvider) parseRMC(fields []string) bool {
+	if len(fields) < 9 || fields[1] != "A" {
+		return false
+	}
+
+	lat, okLat := nmeaCoordinate(fields[2], fields[3], "S")
+	lng, okLng := nmeaCoordinate(fields[4], fields[5], "W")
+	if !okLat || !okLng {
+		return false
+	}
+
+	if n.hasPosition {
+		n.current.CumulativeDistance += Distance(n.current.Latitude, n.current.Longitude, lat, lng)
+	}
+	n.hasPosition = true
+
+	n.current.Latitude = lat
+	n.current.Longitude = lng
+	n.current.SpeedOverGround = nmeaFloat(fields, 6, n.current.SpeedOverGround)
+	n.current.CourseOverGround = nmeaFloat(fields, 7, n.current.CourseOverGround)
+
+	return true
+}
+
+func (n *NMEANavigationDataProvider) parseMWV(fields []string) {
+	// angle,R|T,speed,N|M|K,A
+	if len(fields) < 5 || fields[4] != "A" {
+		return
+	}
+
+	angle, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return
+	}
+	speed, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return
+	}
+
+	switch fields[3] {
+	case "M":
+		speed *= KnotsPerMeterPerSecond
+	case "K":
+		speed *= 1000 / MetersPerNauticalMile
+	}
+
+	if fields[1] == "R" {
+		n.current.ApparentWindAngle = NormalizeAngle(angle)
+		n.current.ApparentWindSpeed = speed
+		n.fields.add(FieldApparentWindAngle, FieldApparentWindSpeed)
+	} else {
+		n.current.TrueWindAngle = NormalizeAngle(angle)
+		n.current.TrueWindSpeed = speed
+		n.fields.add(FieldTrueWindAngle, FieldTrueWindSpeed)
+	}
+}
+
+// parseXDR picks the pitch and roll angles from transducer measurements, other transducers
+// are stored as extra channels by their name
+func (n *NMEANavigationDataProvider) parseXDR(fields []string) {
+	// Groups of type,value,unit,name
+	for i := 0; i+3 < len(fields); i += 4 {
+		value, err := strconv.ParseFloat(fields[i+1], 64)
+		if err != nil {
+			continue
+		}
+
+		name := fields[i+3]
+		switch {
+		case fields[i] == "A" && strings.EqualFold(name, "PTCH"):
+			n.current.Pitch = value
+			n.fields.add(FieldPitch)
+		case fields[i] == "A" && strings.EqualFold(name, "ROLL"):
+			n.current.Roll = value
+			n.fields.add(FieldRoll)
+		case name != "":
+			n.fields.add(FieldExtra)
+			extra := make(map[string]float64, len(n.current.Extra)+1)
+			for k, v := range n.current.Extra {
+				extra[k] = v
+			}
+			extra[name] = value
+			n.current.Extra = extra
+		}
+	}
+}
+
+// parseNMEASentence verifies the checksum and splits the sentence into the sentence
+// type (without the talker id) and the data fields. The time of the NMEA 4.10 tag block is
+// returned if the sentence has one.
+func parseNMEASentence(line string) (string, []string, time.Time, error) {
+	line = strings.TrimSpace(line)
+
+	var tagTime time.Time
+	if strings.HasPrefix(line, "\\") {
+		if end := strings.Index(line[1:], "\\"); end >= 0 {
+			tagTime = nmeaTagBlockTime(line[1 : end+1])
+			line = line[end+2:]
+		}
+	}
+
+	if len(line) < 7 || (line[0] != '$' && line[0] != '!') {
+		return "", nil, tagTime, fmt.Errorf("not a sentence: %q", line)
+	}
+	line = line[1:]
+
+	if star := strings.LastIndex(line, "*"); star >= 0 {
+		var checksum byte
+		for i := 0; i < star; i++ {
+			checksum ^= line[i]
+		}
+		expected, err := strconv.ParseUint(line[star+1:], 16, 8)
+		if err != nil || byte(expected) != checksum {
+			return "", nil, tagTime, fmt.Errorf("checksum mismatch: %q", line)
+		}
+		line = line[:star]
+	}
+
+	fields := strings.Split(line, ",")
+	if len(fields[0]) != 5 {
+		return "", nil, tagTime, fmt.Errorf("unsupported sentence: %q", line)
+	}
+
+	return fields[0][2:], fields[1:], tagTime, nil
+}
+
+// nmeaTagBlockTime returns the UNIX time of the c: parameter of a tag block, in seconds or
+// milliseconds, or zero if there is none
+func nmeaTagBlockTime(tagBlock string) time.Time {
+	if star := strings.LastIndex(tagBlock, "*"); star >= 0 {
+		tagBlock = tagBlock[:star]
+	}
+	for _, param := range strings.Split(tagBlock, ",") {
+		value, ok := strings.CutPrefix(param, "c:")
+		if !ok {
+			continue
+		}
+		unix, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}
+		}
+		// Seconds would be far in the future at this many digits
+		if unix > 1e11 {
+			return time.UnixMilli(unix).UTC()
+		}
+		return time.Unix(unix, 0).UTC()
+	}
+	return time.Time{}
+}
+
+// nmeaCoordinate converts a ddmm.mmmm coordinate to decimal degrees
+func nmeaCoordinate(value, hemisphere, negative string) (float64, bool) {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	degrees := float64(int(v / 100))
+	result := degrees + (v-degrees*100)/60
+	if hemisphere == negative {
+		result = -result
+	}
+	return result, true
+}
+
+// nmeaFloat returns the field value or the default if it's missing or invalid
+func nmeaFloat(fields []string, pos int, def float64) float64 {
+	if pos >= len(fields) {
+		return def
+	}
+	v, err := strconv.ParseFloat(fields[pos], 64)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// nmeaSigned returns the field value, negated unless the direction field is the positive one
+func nmeaSigned(fields []string, pos, dirPos int, positive string) float64 {
+	v := nmeaFloat(fields, pos, 0)
+	if dirPos < len(fields) && fields[dirPos] != positive {
+		v = -v
+	}
+	return v
+}
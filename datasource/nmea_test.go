@@ -0,0 +1,79 @@
+package datasource
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testNMEA = `$GPRMC,120000.00,A,5929.3210,N,02447.9203,E,5.8,87.2,110924,,,A*68
+$IIHDG,78.0,,,5.0,E*18
+$IIMWV,32.0,R,17.6,N,A*3C
+$IIMWV,45.8,T,13.0,N,A*30
+$IIMWV,45.8,T,13.0,N,A*FF
+$IIVHW,,T,,M,5.5,N,,K*7B
+$GPRMC,120001.00,A,5929.3220,N,02447.9253,E,5.9,87.0,110924,,,A*6C
+`
+
+func TestNMEANavigationDataProvider(t *testing.T) {
+	require := require.New(t)
+
+	ds := NewNMEANavigationDataProvider(strings.NewReader(testNMEA))
+
+	d, ok := ds.Next()
+	require.True(ok)
+	require.Equal("2024-09-11T12:00:00Z", d.Timestamp.Format(time.RFC3339))
+	require.InDelta(59.488683, d.Latitude, 0.000001)
+	require.InDelta(24.798672, d.Longitude, 0.000001)
+	require.Equal(5.8, d.SpeedOverGround)
+	require.Equal(87.2, d.CourseOverGround)
+
+	d, ok = ds.Next()
+	require.True(ok)
+	require.Equal("2024-09-11T12:00:01Z", d.Timestamp.Format(time.RFC3339))
+	require.Equal(83.0, d.Heading)
+	require.Equal(32.0, d.ApparentWindAngle)
+	require.Equal(17.6, d.ApparentWindSpeed)
+	require.Equal(45.8, d.TrueWindAngle)
+	require.Equal(13.0, d.TrueWindSpeed)
+	require.InDelta(128.8, d.TrueWindDirection, 0.001)
+	require.Equal(5.5, d.SpeedThroughWater)
+	require.InDelta(0.0027, d.CumulativeDistance, 0.0001)
+
+	_, ok = ds.Next()
+	require.False(ok)
+}
+
+// An instruments log without a position fix, the time comes from ZDA, RMC and a tag block
+const testInstrumentsNMEA = `$GPZDA,120000.00,11,09,2024,00,00*68
+$IIHDT,78.0,T*1D
+$IIDPT,12.5,0.0*76
+$GPRMC,120001.00,V,,,,,,,110924,,,N*70
+$IIMWV,45.8,T,13.0,N,A*30
+\c:1726056002*5A\$IIHDT,80.0,T*1A
+`
+
+func TestNMEAInstrumentsOnly(t *testing.T) {
+	require := require.New(t)
+
+	ds := NewNMEANavigationDataProvider(strings.NewReader(testInstrumentsNMEA))
+	points := ReadAll(ds)
+	require.Len(points, 3)
+
+	require.Equal("2024-09-11T12:00:00Z", points[0].Timestamp.Format(time.RFC3339))
+	require.Equal(78.0, points[0].Heading)
+	require.Equal(12.5, points[0].Depth)
+
+	require.Equal("2024-09-11T12:00:01Z", points[1].Timestamp.Format(time.RFC3339))
+	require.Equal(45.8, points[1].TrueWindAngle)
+	require.Equal(12.5, points[1].Depth)
+	require.InDelta(123.8, points[1].TrueWindDirection, 0.001)
+
+	require.Equal("2024-09-11T12:00:02Z", points[2].Timestamp.Format(time.RFC3339))
+	require.Equal(80.0, points[2].Heading)
+	require.Equal(0.0, points[2].Latitude)
+
+	require.Equal([]Field{FieldHeading, FieldTrueWindAngle, FieldTrueWindSpeed, FieldTrueWindDirection, FieldDepth}, ds.ProvidedFields())
+}
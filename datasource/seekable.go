@@ -0,0 +1,96 @@
+package datasource
+
+import (
+	"sort"
+	"time"
+)
+
+// SeekableNavigationDataProvider is a provider that holds the whole session in memory and
+// allows random access to it
+type SeekableNavigationDataProvider interface {
+	NavigationDataProvider
+	// Reset rewinds the provider so that Next returns the first point again
+	Reset()
+	// Len returns the number of points
+	Len() int
+	// At returns the point at index i without changing the position of Next
+	At(i int) NavigationDataPoint
+	// SeekTime positions the provider so that Next returns the first point at or after t
+	// and returns the index of that point. Len is returned if there are no such points.
+	SeekTime(t time.Time) int
+	// TimeRange returns the timestamps of the first and last point
+	TimeRange() (time.Time, time.Time)
+}
+
+// PointsNavigationDataProvider is a SeekableNavigationDataProvider over a slice of points
+// that are ordered by time
+type PointsNavigationDataProvider struct {
+	points []NavigationDataPoint
+	pos    int
+}
+
+func NewPointsNavigationDataProvider(points []NavigationDataPoint) *PointsNavigationDataProvider {
+	return &PointsNavigationDataProvider{points: points}
+}
+
+// NewSeekableNavigationDataProvider returns the provider itself if it is already seekable,
+// otherwise it reads all the remaining points of the provider into memory
+func NewSeekableNavigationDataProvider(provider NavigationDataProvider) SeekableNavigationDataProvider {
+	if seekable, ok := provider.(SeekableNavigationDataProvider); ok {
+		return seekable
+	}
+	return NewPointsNavigationDataProvider(ReadAll(provider))
+}
+
+// ReadAll returns all the remaining points of the provider
+func ReadAll(provider NavigationDataProvider) []NavigationDataPoint {
+	var points []NavigationDataPoint
+	for {
+		p, ok := provider.Next()
+		if !ok {
+			return points
+		}
+		points = append(points, p)
+	}
+}
+
+func (p *PointsNavigationDataProvider) Next() (NavigationDataPoint, bool) {
+	if p.pos >= len(p.points) {
+		return NavigationDataPoint{}, false
+	}
+	p.pos++
+	return p.points[p.pos-1], true
+}
+
+func (p *PointsNavigationDataProvider) Reset() {
+	p.pos = 0
+}
+
+func (p *PointsNavigationDataProvider) Len() int {
+	return len(p.points)
+}
+
+func (p *PointsNavigationDataProvider) At(i int) NavigationDataPoint {
+	return p.points[i]
+}
+
+func (p *PointsNavigationDataProvider) SeekTime(t time.Time) int {
+	p.pos = sort.Search(len(p.points), func(i int) bool {
+		return !p.points[i].Timestamp.Before(t)
+	})
+	return p.pos
+}
+
+func (p *PointsNavigationDataProvider) TimeRange() (time.Time, time.Time) {
+	if len(p.points) == 0 {
+		return time.Time{}, time.Time{}
+	}
+	return p.points[0].Timestamp, p.points[len(p.points)-1].Timestamp
+}
+
+// GetAllPoints returns a copy of all the points
+func (p *PointsNavigationDataProvider) GetAllPoints() []NavigationDataPoint {
+	points := make([]NavigationDataPoint, len(p.points))
+	copy(points, p.points)
+	return points
+}
@@ -0,0 +1,51 @@
+package datasource
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPointsNavigationDataProvider(t *testing.T) {
+	require := require.New(t)
+
+	start := time.Date(2024, 9, 11, 12, 0, 0, 0, time.UTC)
+	var points []NavigationDataPoint
+	for i := 0; i < 5; i++ {
+		points = append(points, NavigationDataPoint{Timestamp: start.Add(time.Duration(i) * 2 * time.Second), Heading: float64(i)})
+	}
+
+	var ds SeekableNavigationDataProvider = NewPointsNavigationDataProvider(points)
+	require.Equal(5, ds.Len())
+	require.Equal(3.0, ds.At(3).Heading)
+
+	first, last := ds.TimeRange()
+	require.Equal(start, first)
+	require.Equal(start.Add(8*time.Second), last)
+
+	require.Equal(2, ds.SeekTime(start.Add(3*time.Second)))
+	d, ok := ds.Next()
+	require.True(ok)
+	require.Equal(2.0, d.Heading)
+
+	require.Equal(5, ds.SeekTime(start.Add(time.Minute)))
+	_, ok = ds.Next()
+	require.False(ok)
+
+	ds.Reset()
+	d, ok = ds.Next()
+	require.True(ok)
+	require.Equal(0.0, d.Heading)
+}
+
+func TestNewSeekableNavigationDataProvider(t *testing.T) {
+	require := require.New(t)
+
+	points := []NavigationDataPoint{{Heading: 1}, {Heading: 2}}
+	ds := NewSeekableNavigationDataProvider(&sliceProvider{points: points})
+	require.Equal(2, ds.Len())
+
+	seekable := NewPointsNavigationDataProvider(points)
+	require.Same(seekable, NewSeekableNavigationDataProvider(seekable))
+}
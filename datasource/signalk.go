@@ -0,0 +1,266 @@
+package datasource
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"time"
+)
+
+// signalKFields are the fields that the Signal K paths give values for
+var signalKFields = map[string][]Field{
+	"navigation.position":               {FieldPosition, FieldCumulativeDistance},
+	"navigation.courseOverGroundTrue":   {FieldCourseOverGround},
+	"navigation.speedOverGround":        {FieldSpeedOverGround},
+	"navigation.headingTrue":            {FieldHeading},
+	"navigation.headingMagnetic":        {FieldHeading},
+	"navigation.speedThroughWater":      {FieldSpeedThroughWater},
+	"environment.wind.angleApparent":    {FieldApparentWindAngle},
+	"environment.wind.speedApparent":    {FieldApparentWindSpeed},
+	"environment.wind.angleTrueWater":   {FieldTrueWindAngle},
+	"environment.wind.speedTrue":        {FieldTrueWindSpeed},
+	"environment.wind.directionTrue":    {FieldTrueWindDirection},
+	"environment.depth.belowTransducer": {FieldDepth},
+	"environment.water.temperature":     {FieldWaterTemperature},
+	"navigation.rateOfTurn":             {FieldRateOfTurn},
+	"performance.velocityMadeGood":      {FieldVelocityMadeGood},
+}
+
+type signalKDelta struct {
+	Self    string          `json:"self"`
+	Context string          `json:"context"`
+	Updates []signalKUpdate `json:"updates"`
+}
+
+type signalKUpdate struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Values    []signalKValue `json:"values"`
+}
+
+type signalKValue struct {
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// SignalKNavigationDataProvider reads a stream of Signal K delta messages, either from a
+// recorded file or from a server WebSocket stream. Values are converted from the SI units
+// used by Signal K to degrees and knots. The values received with the same timestamp are
+// combined into a single navigation data point, no points are produced before the first
+// position is received. The heading is taken from headingTrue, or from headingMagnetic corrected
+// by magneticVariation when the server doesn't send a true heading. Without a variation the
+// magnetic heading is used as is.
+type SignalKNavigationDataProvider struct {
+	decoder        *json.Decoder
+	closer         io.Closer
+	self           string
+	current        NavigationDataPoint
+	pending        bool
+	hasPosition    bool
+	hasTWD         bool
+	hasTrueHeading bool
+	// magneticHeading is the last magnetic heading, corrected when the variation is received
+	magneticHeading    float64
+	hasMagneticHeading bool
+	variation          float64
+	fields             fieldSet
+	queue              []NavigationDataPoint
+}
+
+func NewSignalKNavigationDataProvider(reader io.Reader) *SignalKNavigationDataProvider {
+	return &SignalKNavigationDataProvider{
+		decoder: json.NewDecoder(reader),
+		fields:  make(fieldSet),
+	}
+}
+
+// DialSignalK connects to the WebSocket delta stream of a Signal K server, for example
+// ws://localhost:3000/signalk/v1/stream?subscribe=self
+func DialSignalK(url string) (*SignalKNavigationDataProvider, error) {
+	ws, err := dialWebSocket(url)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := NewSignalKNavigationDataProvider(ws)
+	provider.closer = ws
+	return provider, nil
+}
+
+// Close closes the WebSocket connection, after which Next returns the last pending point
+func (s *SignalKNavigationDataProvider) Close() error {
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
+
+func (s *SignalKNavigationDataProvider) Next() (NavigationDataPoint, bool) {
+	for len(s.queue) == 0 {
+		var delta signalKDelta
+		if err := s.decoder.Decode(&delta); err != nil {
+			if err != io.EOF {
+				log.Printf("error reading Signal K data: %v", err)
+			}
+			s.flush()
+			if len(s.queue) == 0 {
+				return NavigationDataPoint{}, false
+			}
+			break
+		}
+
+		// Only our own vessel is of interest. The server hello message tells which context is
+		// ours, without it the first vessel seen is assumed to be us.
+		if delta.Self != "" {
+			s.self = delta.Self
+		}
+		if delta.Context != "" && delta.Context != "vessels.self" {
+			if s.self == "" {
+				s.self = delta.Context
+			}
+			if delta.Context != s.self {
+				continue
+			}
+		}
+
+		for _, update := range delta.Updates {
+			if update.Timestamp.IsZero() {
+				continue
+			}
+			if s.pending && !update.Timestamp.Equal(s.current.Timestamp) {
+				s.flush()
+			}
+			s.current.Timestamp = update.Timestamp
+			for _, v := range update.Values {
+				s.applyValue(v)
+			}
+		}
+	}
+
+	p := s.queue[0]
+	s.queue = s.queue[1:]
+	return p, true
+}
+
+// flush queues the point accumulated so far
+func (s *SignalKNavigationDataProvider) flush() {
+	if s.pending && s.hasPosition {
+		p := s.current
+		if !s.hasTWD {
+			p.TrueWindDirection = NormalizeDirection(p.Heading + p.TrueWindAngle)
+		}
+		s.queue = append(s.queue, p)
+	}
+	s.pending = false
+}
+
+func (s *SignalKNavigationDataProvider) applyValue(v signalKValue) {
+	if v.Path == "navigation.position" {
+		var position struct {
+			Latitude  *float64 `json:"latitude"`
+			Longitude *float64 `json:"longitude"`
+		}
+		if err := json.Unmarshal(v.Value, &position); err != nil || position.Latitude == nil || position.Longitude == nil {
+			return
+		}
+		if s.hasPosition {
+			s.current.CumulativeDistance += Distance(s.current.Latitude, s.current.Longitude, *position.Latitude, *position.Longitude)
+		}
+		s.current.Latitude = *position.Latitude
+		s.current.Longitude = *position.Longitude
+		s.hasPosition = true
+		s.pending = true
+		s.fields.add(signalKFields[v.Path]...)
+		return
+	}
+
+	if v.Path == "navigation.attitude" {
+		var attitude struct {
+			Roll  *float64 `json:"roll"`
+			Pitch *float64 `json:"pitch"`
+		}
+		if err := json.Unmarshal(v.Value, &attitude); err != nil {
+			return
+		}
+		if attitude.Roll != nil {
+			s.current.Roll = *attitude.Roll * 180 / math.Pi
+			s.fields.add(FieldRoll)
+		}
+		if attitude.Pitch != nil {
+			s.current.Pitch = *attitude.Pitch * 180 / math.Pi
+			s.fields.add(FieldPitch)
+		}
+		s.pending = true
+		return
+	}
+
+	var value float64
+	if err := json.Unmarshal(v.Value, &value); err != nil {
+		return
+	}
+	degrees := value * 180 / math.Pi
+	knots := value * KnotsPerMeterPerSecond
+
+	switch v.Path {
+	case "navigation.courseOverGroundTrue":
+		s.current.CourseOverGround = NormalizeDirection(degrees)
+	case "navigation.speedOverGround":
+		s.current.SpeedOverGround = knots
+	case "navigation.headingTrue":
+		s.current.Heading = NormalizeDirection(degrees)
+		s.hasTrueHeading = true
+	case "navigation.headingMagnetic":
+		s.magneticHeading = degrees
+		s.hasMagneticHeading = true
+		s.updateMagneticHeading()
+	case "navigation.magneticVariation":
+		// East is positive
+		s.variation = degrees
+		s.updateMagneticHeading()
+	case "navigation.speedThroughWater":
+		s.current.SpeedThroughWater = knots
+	case "environment.wind.angleApparent":
+		s.current.ApparentWindAngle = NormalizeAngle(degrees)
+	case "environment.wind.speedApparent":
+		s.current.ApparentWindSpeed = knots
+	case "environment.wind.angleTrueWater":
+		s.current.TrueWindAngle = NormalizeAngle(degrees)
+	case "environment.wind.speedTrue":
+		s.current.TrueWindSpeed = knots
+	case "environment.wind.directionTrue":
+		s.current.TrueWindDirection = NormalizeDirection(degrees)
+		s.hasTWD = true
+	case "environment.depth.belowTransducer":
+		s.current.Depth = value
+	case "environment.water.temperature":
+		s.current.WaterTemperature = value - kelvinOffset
+		s.current.HasWaterTemperature = true
+	case "navigation.rateOfTurn":
+		s.current.RateOfTurn = degrees * 60
+	case "performance.velocityMadeGood":
+		s.current.VelocityMadeGood = knots
+	default:
+		return
+	}
+
+	s.pending = true
+	s.fields.add(signalKFields[v.Path]...)
+	// The true wind direction is computed from the heading and the true wind angle without it
+	if s.fields[FieldHeading] && s.fields[FieldTrueWindAngle] {
+		s.fields.add(FieldTrueWindDirection)
+	}
+}
+
+// ProvidedFields returns the fields that the deltas read so far had values for
+func (s *SignalKNavigationDataProvider) ProvidedFields() []Field {
+	return s.fields.list()
+}
+
+// updateMagneticHeading sets the heading from the magnetic heading and the variation, unless
+// there is a true heading
+func (s *SignalKNavigationDataProvider) updateMagneticHeading() {
+	if s.hasTrueHeading || !s.hasMagneticHeading {
+		return
+	}
+	s.current.Heading = NormalizeDirection(s.magneticHeading + s.variation)
+}
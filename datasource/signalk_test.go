@@ -0,0 +1,156 @@
+package datasource
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testSignalKDeltas = `{"name":"signalk-server","version":"2.8.0","self":"vessels.urn:mrn:imo:mmsi:276000001","roles":["master"]}
+{"context":"vessels.urn:mrn:imo:mmsi:276000001","updates":[{"timestamp":"2024-09-11T14:27:52.000Z","values":[
+  {"path":"navigation.position","value":{"longitude":24.798672,"latitude":59.488683}},
+  {"path":"navigation.courseOverGroundTrue","value":1.5216},
+  {"path":"navigation.speedOverGround","value":3.0}]}]}
+{"context":"vessels.urn:mrn:imo:mmsi:276000001","updates":[{"timestamp":"2024-09-11T14:27:52.000Z","values":[
+  {"path":"navigation.headingTrue","value":1.3631},
+  {"path":"environment.wind.angleApparent","value":0.5585},
+  {"path":"environment.wind.speedApparent","value":9.05},
+  {"path":"environment.wind.angleTrueWater","value":-0.7992},
+  {"path":"environment.wind.speedTrue","value":6.69}]}]}
+{"context":"vessels.urn:mrn:imo:mmsi:230999999","updates":[{"timestamp":"2024-09-11T14:27:52.500Z","values":[
+  {"path":"navigation.position","value":{"longitude":24.0,"latitude":59.0}}]}]}
+{"context":"vessels.urn:mrn:imo:mmsi:276000001","updates":[{"timestamp":"2024-09-11T14:27:53.000Z","values":[
+  {"path":"navigation.position","value":{"longitude":24.798722,"latitude":59.488683}},
+  {"path":"environment.wind.directionTrue","value":2.3081}]}]}
+`
+
+func TestSignalKNavigationDataProvider(t *testing.T) {
+	require := require.New(t)
+
+	ds := NewSignalKNavigationDataProvider(strings.NewReader(testSignalKDeltas))
+
+	d, ok := ds.Next()
+	require.True(ok)
+	require.Equal("2024-09-11T14:27:52Z", d.Timestamp.Format(time.RFC3339))
+	require.Equal(59.488683, d.Latitude)
+	require.Equal(24.798672, d.Longitude)
+	require.InDelta(87.18, d.CourseOverGround, 0.01)
+	require.InDelta(5.83, d.SpeedOverGround, 0.01)
+	require.InDelta(78.1, d.Heading, 0.01)
+	require.InDelta(32.0, d.ApparentWindAngle, 0.01)
+	require.InDelta(17.59, d.ApparentWindSpeed, 0.01)
+	require.InDelta(-45.79, d.TrueWindAngle, 0.01)
+	require.InDelta(13.0, d.TrueWindSpeed, 0.01)
+	require.InDelta(32.31, d.TrueWindDirection, 0.01)
+
+	d, ok = ds.Next()
+	require.True(ok)
+	require.Equal("2024-09-11T14:27:53Z", d.Timestamp.Format(time.RFC3339))
+	require.Equal(24.798722, d.Longitude)
+	require.InDelta(132.25, d.TrueWindDirection, 0.01)
+	require.InDelta(0.0015, d.CumulativeDistance, 0.0001)
+
+	_, ok = ds.Next()
+	require.False(ok)
+}
+
+func TestSignalKMagneticHeading(t *testing.T) {
+	require := require.New(t)
+
+	deltas := `{"updates":[{"timestamp":"2024-09-11T14:27:52.000Z","values":[
+  {"path":"navigation.position","value":{"longitude":24.798672,"latitude":59.488683}},
+  {"path":"navigation.headingMagnetic","value":1.3631},
+  {"path":"navigation.magneticVariation","value":0.1309}]}]}
+{"updates":[{"timestamp":"2024-09-11T14:27:53.000Z","values":[
+  {"path":"navigation.headingTrue","value":1.0},
+  {"path":"navigation.headingMagnetic","value":1.3631}]}]}
+`
+	ds := NewSignalKNavigationDataProvider(strings.NewReader(deltas))
+
+	// 78.1 magnetic with 7.5 E variation
+	d, ok := ds.Next()
+	require.True(ok)
+	require.InDelta(85.6, d.Heading, 0.01)
+
+	// The true heading wins over the magnetic one
+	d, ok = ds.Next()
+	require.True(ok)
+	require.InDelta(57.3, d.Heading, 0.01)
+}
+
+func TestDialSignalK(t *testing.T) {
+	require := require.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Sec-WebSocket-Key")
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		require.NoError(err)
+		defer conn.Close()
+
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		rw.WriteString("Sec-WebSocket-Accept: " + webSocketAccept(key) + "\r\n\r\n")
+
+		writeServerFrame(rw, wsOpPing, "ping")
+		for _, line := range strings.Split(strings.TrimSpace(testSignalKDeltas), "\n{") {
+			if !strings.HasPrefix(line, "{") {
+				line = "{" + line
+			}
+			writeServerFrame(rw, wsOpText, line)
+		}
+		writeServerFrame(rw, wsOpClose, "")
+		rw.Flush()
+	}))
+	defer server.Close()
+
+	ds, err := DialSignalK("ws" + strings.TrimPrefix(server.URL, "http") + "/signalk/v1/stream?subscribe=self")
+	require.NoError(err)
+	defer ds.Close()
+
+	var points []NavigationDataPoint
+	for {
+		p, ok := ds.Next()
+		if !ok {
+			break
+		}
+		points = append(points, p)
+	}
+	require.Len(points, 2)
+	require.InDelta(132.25, points[1].TrueWindDirection, 0.01)
+}
+
+func TestWebSocketInvalidFrames(t *testing.T) {
+	require := require.New(t)
+
+	frames := map[string][]byte{
+		"long ping":       append([]byte{0x80 | wsOpPing, 126, 0x01, 0x00}, make([]byte, 256)...),
+		"fragmented ping": {wsOpPing, 0},
+		"huge frame":      {0x80 | wsOpText, 127, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"length top bit":  {0x80 | wsOpText, 127, 0x80, 0, 0, 0, 0, 0, 0, 0},
+		"unknown opcode":  {0x80 | 0x3, 0},
+	}
+	for name, frame := range frames {
+		ws := &webSocketReader{reader: bufio.NewReader(bytes.NewReader(frame))}
+		_, err := ws.Read(make([]byte, 10))
+		require.Error(err, name)
+		require.NotEqual(io.EOF, err, name)
+	}
+}
+
+func writeServerFrame(w *bufio.ReadWriter, opcode byte, payload string) {
+	w.WriteByte(0x80 | opcode)
+	if len(payload) < 126 {
+		w.WriteByte(byte(len(payload)))
+	} else {
+		w.WriteByte(126)
+		w.WriteByte(byte(len(payload) >> 8))
+		w.WriteByte(byte(len(payload)))
+	}
+	w.WriteString(payload)
+}
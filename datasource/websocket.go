@@ -0,0 +1,268 @@
+package datasource
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xa
+)
+
+// maxControlFrameLength is the longest payload of a control frame allowed by RFC 6455
+const maxControlFrameLength = 125
+
+// maxDataFrameLength is the longest data frame accepted, the Signal K deltas are far shorter
+const maxDataFrameLength = 1 << 20
+
+// webSocketReader is a minimal RFC 6455 client that presents the payloads of the received
+// data messages as a single stream. It only supports what is needed for reading a stream
+// of JSON messages from a server.
+type webSocketReader struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	remaining uint64
+	mask      []byte
+	maskPos   int
+	writeMu   sync.Mutex
+}
+
+// dialWebSocket connects to a ws:// or wss:// URL
+func dialWebSocket(rawURL string) (*webSocketReader, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	host := u.Host
+	var conn net.Conn
+	switch u.Scheme {
+	case "ws":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+		conn, err = net.Dial("tcp", host)
+	case "wss":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		}
+		conn, err = tls.Dial("tcp", host, &tls.Config{ServerName: u.Hostname()})
+	default:
+		return nil, fmt.Errorf("unsupported websocket scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keyBytes := make([]byte, 16)
+	if _, err := rand.Read(keyBytes); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	req.URL.Scheme = "http"
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("websocket handshake failed: %s", resp.Status)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != webSocketAccept(key) {
+		conn.Close()
+		return nil, fmt.Errorf("websocket handshake failed: invalid accept key")
+	}
+
+	return &webSocketReader{conn: conn, reader: reader}, nil
+}
+
+// webSocketAccept returns the Sec-WebSocket-Accept value expected for the key
+func webSocketAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func (ws *webSocketReader) Read(p []byte) (int, error) {
+	for ws.remaining == 0 {
+		if err := ws.nextDataFrame(); err != nil {
+			return 0, err
+		}
+	}
+
+	if uint64(len(p)) > ws.remaining {
+		p = p[:ws.remaining]
+	}
+	n, err := ws.reader.Read(p)
+	for i := 0; i < n && ws.mask != nil; i++ {
+		p[i] ^= ws.mask[ws.maskPos%4]
+		ws.maskPos++
+	}
+	ws.remaining -= uint64(n)
+
+	return n, err
+}
+
+// nextDataFrame reads frame headers until the start of a data frame, answering pings and
+// returning io.EOF when the server closes the connection
+func (ws *webSocketReader) nextDataFrame() error {
+	for {
+		opcode, length, mask, err := ws.readFrameHeader()
+		if err != nil {
+			return err
+		}
+		if opcode != wsOpClose && opcode != wsOpPing && opcode != wsOpPong && length > maxDataFrameLength {
+			return fmt.Errorf("websocket frame of %d bytes is too long", length)
+		}
+
+		switch opcode {
+		case wsOpText, wsOpBinary, wsOpContinuation:
+			ws.remaining = length
+			ws.mask = mask
+			ws.maskPos = 0
+			return nil
+		case wsOpClose:
+			ws.writeFrame(wsOpClose, nil)
+			return io.EOF
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(ws.reader, payload); err != nil {
+			return err
+		}
+		for i := range payload {
+			if mask != nil {
+				payload[i] ^= mask[i%4]
+			}
+		}
+		if opcode == wsOpPing {
+			if err := ws.writeFrame(wsOpPong, payload); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readFrameHeader reads the header of a frame and checks that the frame is valid
+func (ws *webSocketReader) readFrameHeader() (byte, uint64, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(ws.reader, header); err != nil {
+		return 0, 0, nil, err
+	}
+
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0f
+	length := uint64(header[1] & 0x7f)
+
+	switch opcode {
+	case wsOpContinuation, wsOpText, wsOpBinary:
+	case wsOpClose, wsOpPing, wsOpPong:
+		// Control frames are short and can't be fragmented, the length fits in the first byte
+		if !fin || length > maxControlFrameLength {
+			return 0, 0, nil, errors.New("invalid websocket control frame")
+		}
+	default:
+		return 0, 0, nil, fmt.Errorf("unsupported websocket opcode %d", opcode)
+	}
+
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(ws.reader, ext); err != nil {
+			return 0, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(ws.reader, ext); err != nil {
+			return 0, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext)
+		if length>>63 != 0 {
+			return 0, 0, nil, errors.New("invalid websocket frame length")
+		}
+	}
+
+	var mask []byte
+	if header[1]&0x80 != 0 {
+		mask = make([]byte, 4)
+		if _, err := io.ReadFull(ws.reader, mask); err != nil {
+			return 0, 0, nil, err
+		}
+	}
+
+	return opcode, length, mask, nil
+}
+
+// writeFrame sends a single masked frame, as required for frames sent by the client
+func (ws *webSocketReader) writeFrame(opcode byte, payload []byte) error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+
+	frame := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, 0x80|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return err
+	}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	_, err := ws.conn.Write(frame)
+	return err
+}
+
+func (ws *webSocketReader) Close() error {
+	return ws.conn.Close()
+}
@@ -1,9 +1,15 @@
 package gosailing
 
-import "math"
+import (
+	"math"
+
+	"github.com/gopxl/pixel/v2"
+)
 
 const (
 	TackAngle = 45.0
+	// GybeAngle is how far off dead downwind the boats sail on the downwind legs
+	GybeAngle = 45.0
 )
 
 // RotatePoint rotates a point (x, y) by n degrees around the specified origin (ox, oy)
@@ -25,18 +31,32 @@ func RotatePoint(x, y, ox, oy, degrees float64) (float64, float64) {
 	return xNew, yNew
 }
 
-// LatLngToScreen converts latitude and longitude to screen coordinates. The zoom value is approximately the
-// number of pixels per degree of latitude and longitude. One nautical mile is 1/60 degrees of latitude,
-// so for zoom=1, 60 nautical miles would be ~1 pixel (0.0166 pixels per nm). If we want 1 nm to be 100 pixels,
-// we need zoom=100/0.0166=5993.6
-func LatLngToScreen(latitude, longitude float64, zoom float64) (float64, float64) {
-	const tileSize = 512
+// ScreenTransform maps the local plane in metres to screen pixels. The plane is only scaled and
+// shifted, any rotation is done before the transform.
+type ScreenTransform struct {
+	MetersPerPixel float64
+	// OffsetX and OffsetY are the screen location of the origin of the plane
+	OffsetX float64
+	OffsetY float64
+}
+
+// ToScreen converts a location on the plane to screen coordinates
+func (st *ScreenTransform) ToScreen(x, y float64) (float64, float64) {
+	return x/st.MetersPerPixel + st.OffsetX, y/st.MetersPerPixel + st.OffsetY
+}
 
-	sinY := math.Sin(toRadians(latitude))
-	sinY = math.Min(math.Max(sinY, -0.9999), 0.9999)
+// ToPlane converts screen coordinates back to the plane, it is the inverse of ToScreen
+func (st *ScreenTransform) ToPlane(x, y float64) (float64, float64) {
+	return (x - st.OffsetX) * st.MetersPerPixel, (y - st.OffsetY) * st.MetersPerPixel
+}
 
-	return (tileSize*(0.5+longitude/360) + 100) * zoom,
-		(tileSize * (0.5 + math.Log((1+sinY)/(1-sinY))/(4*math.Pi))) * zoom
+// Matrix returns the matrix that moves what was drawn with the other transform to where this
+// transform draws it
+func (st *ScreenTransform) Matrix(from ScreenTransform) pixel.Matrix {
+	return pixel.IM.
+		Moved(pixel.V(-from.OffsetX, -from.OffsetY)).
+		Scaled(pixel.ZV, from.MetersPerPixel/st.MetersPerPixel).
+		Moved(pixel.V(st.OffsetX, st.OffsetY))
 }
 
 func toRadians(degrees float64) float64 {
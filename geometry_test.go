@@ -1,9 +1,9 @@
 package gosailing
 
 import (
-	"fmt"
 	"testing"
 
+	"github.com/gopxl/pixel/v2"
 	"github.com/stretchr/testify/require"
 )
 
@@ -26,25 +26,30 @@ func TestRotatePoint(t *testing.T) {
 	require.InDelta(float64(-10.0), ry, 0.001)
 }
 
-func TestLatLngToScreen(t *testing.T) {
+func TestScreenTransform(t *testing.T) {
 	require := require.New(t)
 
-	lat, lng := 59.0, 24.0
-	zoom := 1.0
-	x, y := LatLngToScreen(lat, lng, zoom)
-	fmt.Printf("x: %f, y: %f\n", x, y)
-	require.InDelta(float64(390.13), x, 0.01)
-	require.InDelta(float64(360.51), y, 0.01)
-
-	// Latitude changes by one degree, expect a ~1 pixel difference in Y
-	x, y = LatLngToScreen(lat+1, lng, zoom)
-	fmt.Printf("lat+1 x: %f, y: %f\n", x, y)
-	require.InDelta(float64(390.13), x, 0.01)
-	require.InDelta(float64(363.31), y, 0.01)
-
-	// Longitude changes by one degree, expect a ~1 pixel difference in X
-	x, y = LatLngToScreen(lat, lng+1, zoom)
-	fmt.Printf("lng+1 x: %f, y: %f\n", x, y)
-	require.InDelta(float64(391.55), x, 0.01)
-	require.InDelta(float64(360.51), y, 0.01)
+	st := &ScreenTransform{MetersPerPixel: 5, OffsetX: 500, OffsetY: 100}
+
+	x, y := st.ToScreen(0, 0)
+	require.InDelta(500.0, x, 0.001)
+	require.InDelta(100.0, y, 0.001)
+
+	// One kilometre north east is 200 pixels up and right
+	x, y = st.ToScreen(1000, 1000)
+	require.InDelta(700.0, x, 0.001)
+	require.InDelta(300.0, y, 0.001)
+
+	px, py := st.ToPlane(x, y)
+	require.InDelta(1000.0, px, 0.001)
+	require.InDelta(1000.0, py, 0.001)
+
+	// Zoomed in and panned, what was drawn before is moved to where the plane is now
+	before := *st
+	st.MetersPerPixel = 2
+	st.OffsetX, st.OffsetY = 300, 50
+	v := st.Matrix(before).Project(pixel.V(700, 300))
+	x, y = st.ToScreen(1000, 1000)
+	require.InDelta(x, v.X, 0.001)
+	require.InDelta(y, v.Y, 0.001)
 }
@@ -0,0 +1,185 @@
+package gosailing
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gopxl/pixel/v2/ext/imdraw"
+	"github.io/mpihlak/gosailing/datasource"
+	"golang.org/x/image/colornames"
+)
+
+// GameMetersPerPixel is the scale of the game for the recorded runs. The boat moves one
+// pixel per tick, so at one tick per second this makes it sail at about 6 knots.
+const GameMetersPerPixel = 3.0
+
+// GhostPoint is the location and heading of the ghost boat at one simulation tick
+type GhostPoint struct {
+	X       float64
+	Y       float64
+	Heading float64
+}
+
+// GhostTrack is a previous run that is raced against, one point per simulation tick
+type GhostTrack struct {
+	Points         []GhostPoint
+	MetersPerPixel float64
+}
+
+// ReadGhostTrack reads a run that was recorded from the game with GhostTrack.Write
+func ReadGhostTrack(reader io.Reader) (*GhostTrack, error) {
+	records, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 2 {
+		return nil, errors.New("no ghost track points found")
+	}
+
+	track := &GhostTrack{MetersPerPixel: GameMetersPerPixel}
+	// The first record is the header
+	for i, record := range records[1:] {
+		if len(record) < 3 {
+			return nil, fmt.Errorf("line %d: expected x,y,heading", i+2)
+		}
+
+		var values [3]float64
+		for j := range values {
+			values[j], err = strconv.ParseFloat(record[j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+2, err)
+			}
+		}
+		track.Points = append(track.Points, GhostPoint{X: values[0], Y: values[1], Heading: values[2]})
+	}
+
+	return track, nil
+}
+
+// Write writes the track in the CSV format that is read by ReadGhostTrack
+func (gt *GhostTrack) Write(writer io.Writer) error {
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.Write([]string{"x", "y", "heading"}); err != nil {
+		return err
+	}
+
+	for _, p := range gt.Points {
+		record := []string{
+			strconv.FormatFloat(p.X, 'f', 2, 64),
+			strconv.FormatFloat(p.Y, 'f', 2, 64),
+			strconv.FormatFloat(p.Heading, 'f', 1, 64),
+		}
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// GhostTrackFromLog converts a real sailing log to a ghost track that starts at the boat location.
+// One tick is one second of the log and the track is scaled so that the median SOG of the log
+// matches the speed of the game boat. The log is rotated so that the mean wind blows from the
+// top of the screen, like in the game.
+func GhostTrackFromLog(points []datasource.NavigationDataPoint, startX, startY float64) (*GhostTrack, error) {
+	if len(points) < 2 {
+		return nil, errors.New("not enough navigation data points for a ghost track")
+	}
+
+	speeds := make([]float64, len(points))
+	for i, p := range points {
+		speeds[i] = p.SpeedOverGround
+	}
+	sort.Float64s(speeds)
+	medianSpeed := speeds[len(speeds)/2]
+	if medianSpeed <= 0 {
+		return nil, errors.New("the log has no boat speed")
+	}
+
+	// One pixel per second at the median speed
+	pixelsPerNm := 3600 / medianSpeed
+	windDirections := make([]float64, len(points))
+	for i, p := range points {
+		windDirections[i] = p.TrueWindDirection
+	}
+	// The wind is averaged on the circle so that a northerly around 0 degrees doesn't turn south
+	meanWind := datasource.MeanAngle(windDirections)
+
+	first := points[0]
+	lngScale := math.Cos(toRadians(first.Latitude))
+	toScreen := func(lat, lng float64) (float64, float64) {
+		x := (lng - first.Longitude) * 60 * lngScale * pixelsPerNm
+		y := (lat - first.Latitude) * 60 * pixelsPerNm
+		x, y = RotatePoint(x, y, 0, 0, -meanWind)
+		return startX + x, startY + y
+	}
+
+	track := &GhostTrack{MetersPerPixel: datasource.MetersPerNauticalMile / pixelsPerNm}
+
+	// Resample the log at one point per second
+	pos := 0
+	last := points[len(points)-1].Timestamp
+	for t := first.Timestamp; !t.After(last); t = t.Add(time.Second) {
+		for pos < len(points)-2 && points[pos+1].Timestamp.Before(t) {
+			pos++
+		}
+
+		p, next := points[pos], points[pos+1]
+		f := 0.0
+		if interval := next.Timestamp.Sub(p.Timestamp); interval > 0 {
+			f = min(1, max(0, float64(t.Sub(p.Timestamp))/float64(interval)))
+		}
+
+		x, y := toScreen(p.Latitude+(next.Latitude-p.Latitude)*f, p.Longitude+(next.Longitude-p.Longitude)*f)
+		heading := p.CourseOverGround + datasource.NormalizeAngle(next.CourseOverGround-p.CourseOverGround)*f
+		track.Points = append(track.Points, GhostPoint{X: x, Y: y, Heading: heading - meanWind})
+	}
+
+	return track, nil
+}
+
+// Ghost is a translucent boat that sails a previous run in lock-step with the simulation
+type Ghost struct {
+	track *GhostTrack
+	boat  *Boat
+}
+
+func NewGhost(track *GhostTrack) *Ghost {
+	first := track.Points[0]
+	boat := NewBoat(first.X, first.Y, 0)
+	boat.SetLaylines(false)
+	boat.SetColor(Translucent(colornames.Dimgray, 0.4))
+	boat.ResetLocation(first.X, first.Y, first.Heading, 0)
+
+	return &Ghost{
+		track: track,
+		boat:  boat,
+	}
+}
+
+// SetTick moves the ghost to its location at the simulation tick. The ghost stays at
+// its last location after the recorded run has ended.
+func (g *Ghost) SetTick(tick int) {
+	p := g.track.Points[min(max(0, tick), len(g.track.Points)-1)]
+	g.boat.SetLocation(p.X, p.Y, p.Heading, 0)
+}
+
+func (g *Ghost) GetXY() (float64, float64) {
+	return g.boat.GetXY()
+}
+
+// MetersPerPixel returns the scale of the ghost track
+func (g *Ghost) MetersPerPixel() float64 {
+	return g.track.MetersPerPixel
+}
+
+func (g *Ghost) Drawable() *imdraw.IMDraw {
+	return g.boat.Drawable()
+}
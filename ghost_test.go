@@ -0,0 +1,68 @@
+package gosailing
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+func TestGhostTrackRoundTrip(t *testing.T) {
+	require := require.New(t)
+
+	track := &GhostTrack{
+		Points: []GhostPoint{
+			{X: 512, Y: 25, Heading: -45},
+			{X: 511.29, Y: 25.71, Heading: -45},
+		},
+		MetersPerPixel: GameMetersPerPixel,
+	}
+
+	var buf bytes.Buffer
+	require.NoError(track.Write(&buf))
+
+	loaded, err := ReadGhostTrack(&buf)
+	require.NoError(err)
+	require.Equal(track, loaded)
+}
+
+func TestGhostTrackFromLog(t *testing.T) {
+	require := require.New(t)
+
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	// Sailing north at 6 knots for a minute, wind from the north
+	points := []datasource.NavigationDataPoint{
+		{Timestamp: start, Latitude: 59.0, Longitude: 24.0, SpeedOverGround: 6},
+		{Timestamp: start.Add(time.Minute), Latitude: 59.0 + 0.1/60, Longitude: 24.0, SpeedOverGround: 6},
+	}
+
+	track, err := GhostTrackFromLog(points, 100, 25)
+	require.NoError(err)
+
+	// One point per second of the log, moving one pixel per second upwards
+	require.Len(track.Points, 61)
+	require.InDelta(100, track.Points[0].X, 0.001)
+	require.InDelta(25, track.Points[0].Y, 0.001)
+	require.InDelta(100, track.Points[60].X, 0.001)
+	require.InDelta(85, track.Points[60].Y, 0.001)
+	require.InDelta(6*datasource.MetersPerNauticalMile/3600, track.MetersPerPixel, 0.001)
+}
+
+func TestGhostTrackFromLogNortherly(t *testing.T) {
+	require := require.New(t)
+
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	// Sailing north with the wind shifting either side of north
+	points := []datasource.NavigationDataPoint{
+		{Timestamp: start, Latitude: 59.0, Longitude: 24.0, SpeedOverGround: 6, TrueWindDirection: 350},
+		{Timestamp: start.Add(time.Minute), Latitude: 59.0 + 0.1/60, Longitude: 24.0, SpeedOverGround: 6, TrueWindDirection: 10},
+	}
+
+	track, err := GhostTrackFromLog(points, 100, 25)
+	require.NoError(err)
+
+	require.InDelta(100, track.Points[60].X, 0.001)
+	require.InDelta(85, track.Points[60].Y, 0.001)
+}
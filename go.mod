@@ -4,6 +4,7 @@ go 1.23.2
 
 require (
 	github.com/gopxl/pixel/v2 v2.3.0
+	github.com/stretchr/testify v1.10.0
 	golang.org/x/image v0.19.0
 )
 
@@ -17,6 +18,5 @@ require (
 	github.com/gopxl/pixel v1.0.0 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stretchr/testify v1.10.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,122 @@
+package gosailing
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.io/mpihlak/gosailing/analysis"
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+// DefaultInstrumentPanel are the instruments shown in the replay unless configured otherwise
+const DefaultInstrumentPanel = "hdg,twd,twa,tws,aws,awa,sog,stw,vmg,vmc,target,polar,btm,dtm,laystbd,layport,heel,dpt"
+
+// InstrumentData is the data that the instruments display
+type InstrumentData struct {
+	Point       datasource.NavigationDataPoint
+	Performance analysis.Performance
+	// StartLine is the start line of the course, nil when it is not known
+	StartLine *datasource.Line
+}
+
+// Instrument is one value shown in the instrument panel
+type Instrument struct {
+	// Name is used to select the instrument in the panel configuration
+	Name  string
+	Label string
+	Value func(d InstrumentData) string
+}
+
+// Instruments are all the instruments that can be put on the panel
+var Instruments = []Instrument{
+	{"hdg", "HDG", func(d InstrumentData) string { return fmt.Sprintf("%03.0f", d.Point.Heading) }},
+	{"twd", "TWD", func(d InstrumentData) string { return fmt.Sprintf("%03.0f", d.Point.TrueWindDirection) }},
+	{"twa", "TWA", func(d InstrumentData) string { return fmt.Sprintf("%03.0f", d.Point.TrueWindAngle) }},
+	{"tws", "TWS", func(d InstrumentData) string { return fmt.Sprintf("%.1f", d.Point.TrueWindSpeed) }},
+	{"aws", "AWS", func(d InstrumentData) string { return fmt.Sprintf("%.1f", d.Point.ApparentWindSpeed) }},
+	{"awa", "AWA", func(d InstrumentData) string { return fmt.Sprintf("%03.0f", d.Point.ApparentWindAngle) }},
+	{"sog", "SOG", func(d InstrumentData) string { return fmt.Sprintf("%.2f", d.Point.SpeedOverGround) }},
+	{"stw", "STW", func(d InstrumentData) string { return fmt.Sprintf("%.2f", d.Point.SpeedThroughWater) }},
+	{"vmg", "VMG", func(d InstrumentData) string { return fmt.Sprintf("%.2f", d.Performance.VMG) }},
+	{"vmc", "VMC", func(d InstrumentData) string { return fmt.Sprintf("%.2f", d.Performance.VMC) }},
+	{"target", "TGT", func(d InstrumentData) string {
+		if !d.Performance.HasPolar() {
+			return "-"
+		}
+		return fmt.Sprintf("%.2f", d.Performance.TargetSpeed)
+	}},
+	{"polar", "POLAR", func(d InstrumentData) string {
+		if !d.Performance.HasPolar() {
+			return "-"
+		}
+		return fmt.Sprintf("%.0f%%", d.Performance.PolarPercentage)
+	}},
+	{"btm", "BTM", func(d InstrumentData) string { return fmt.Sprintf("%03.0f", d.Performance.BearingToMark) }},
+	{"dtm", "DTM", func(d InstrumentData) string { return fmt.Sprintf("%.2f nm", d.Performance.DistanceToMark) }},
+	{"laystbd", "LAY STBD", func(d InstrumentData) string {
+		return formatLayline(d.Performance.StarboardLayline, d.Performance.StarboardLaylineTime)
+	}},
+	{"layport", "LAY PORT", func(d InstrumentData) string {
+		return formatLayline(d.Performance.PortLayline, d.Performance.PortLaylineTime)
+	}},
+	{"heel", "HEEL", func(d InstrumentData) string { return fmt.Sprintf("%03.0f", d.Point.Roll) }},
+	{"pitch", "PITCH", func(d InstrumentData) string { return fmt.Sprintf("%.1f", d.Point.Pitch) }},
+	{"dpt", "DPT", func(d InstrumentData) string { return fmt.Sprintf("%.1f", d.Point.Depth) }},
+	{"mtw", "MTW", func(d InstrumentData) string {
+		if !d.Point.HasWaterTemperature {
+			return "-"
+		}
+		return fmt.Sprintf("%.1f", d.Point.WaterTemperature)
+	}},
+	{"rot", "ROT", func(d InstrumentData) string { return fmt.Sprintf("%.1f", d.Point.RateOfTurn) }},
+	{"bias", "BIAS", func(d InstrumentData) string {
+		if d.StartLine == nil {
+			return "-"
+		}
+		return formatLineBias(*d.StartLine, d.Point.TrueWindDirection)
+	}},
+}
+
+// ParseInstrumentPanel returns the instruments for a comma separated list of instrument names
+func ParseInstrumentPanel(config string) ([]Instrument, error) {
+	var panel []Instrument
+	for _, name := range strings.Split(config, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		found := false
+		for _, instrument := range Instruments {
+			if instrument.Name == name {
+				panel = append(panel, instrument)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown instrument %q", name)
+		}
+	}
+	return panel, nil
+}
+
+// formatLineBias shows the bias of the line in degrees and metres and its favoured end
+func formatLineBias(line datasource.Line, twd float64) string {
+	bias := line.Bias(twd)
+	end := datasource.FavouredEnd(bias)
+	if end == datasource.FavouredSquare {
+		return end
+	}
+	return fmt.Sprintf("%.0f deg %s %.0f m", math.Abs(bias), end, math.Abs(line.BiasDistance(twd)))
+}
+
+// formatLayline shows the distance and time to the layline, or that it's been overstood
+func formatLayline(distance float64, duration time.Duration) string {
+	if distance < 0 {
+		return "overstood"
+	}
+	return fmt.Sprintf("%.2f nm %s", distance, formatClock(duration))
+}
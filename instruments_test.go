@@ -0,0 +1,45 @@
+package gosailing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.io/mpihlak/gosailing/analysis"
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+func TestParseInstrumentPanel(t *testing.T) {
+	require := require.New(t)
+
+	panel, err := ParseInstrumentPanel("sog, VMG,polar")
+	require.NoError(err)
+	require.Len(panel, 3)
+	require.Equal("sog", panel[0].Name)
+	require.Equal("vmg", panel[1].Name)
+
+	// Targets are not shown without a polar
+	require.Equal("-", panel[2].Value(InstrumentData{}))
+	require.Equal("95%", panel[2].Value(InstrumentData{
+		Performance: analysis.Performance{TargetSpeed: 6, PolarPercentage: 95},
+	}))
+
+	// The line bias needs the start line of the course
+	panel, err = ParseInstrumentPanel("bias")
+	require.NoError(err)
+	require.Equal("-", panel[0].Value(InstrumentData{}))
+	line := datasource.Line{
+		Boat: datasource.LatLng{Latitude: 59.45, Longitude: 24.752},
+		Pin:  datasource.LatLng{Latitude: 59.45, Longitude: 24.748},
+	}
+	require.Equal("10 deg boat 39 m", panel[0].Value(InstrumentData{
+		Point:     datasource.NavigationDataPoint{TrueWindDirection: 10},
+		StartLine: &line,
+	}))
+
+	_, err = ParseInstrumentPanel("sog,speedo")
+	require.Error(err)
+
+	panel, err = ParseInstrumentPanel(DefaultInstrumentPanel)
+	require.NoError(err)
+	require.NotEmpty(panel)
+}
@@ -0,0 +1,76 @@
+package gosailing
+
+import (
+	"fmt"
+
+	"github.com/gopxl/pixel/v2"
+	"github.com/gopxl/pixel/v2/backends/opengl"
+	"github.com/gopxl/pixel/v2/ext/text"
+	"golang.org/x/image/colornames"
+)
+
+// maxLeaderboardLines is how many of the best runs are shown at the finish
+const maxLeaderboardLines = 10
+
+// Result returns the result of the finished run, the player, scenario and date are left for the
+// caller to fill in
+func (sr *SailRace) Result() RaceResult {
+	result := RaceResult{
+		Ticks:    sr.raceTick(),
+		Distance: (sr.boat.GetSailedDistance() - sr.startDistance) * GameMetersPerPixel,
+		Tacks:    sr.tacks,
+	}
+	for _, r := range sr.rounding.Roundings() {
+		if r.Flagged() {
+			result.Flags++
+		}
+	}
+	return result
+}
+
+// SetLeaderboard shows the runs of the scenario at the finish, best first. The run that was just
+// finished is marked and the personal best of every player is highlighted.
+func (sr *SailRace) SetLeaderboard(board []RaceResult, run RaceResult) {
+	sr.leaderboard = board
+	sr.run = run
+}
+
+func (sr *SailRace) drawLeaderboard(win *opengl.Window, atlas *text.Atlas) {
+	bounds := win.Bounds()
+	boardTxt := text.New(pixel.V(bounds.Center().X-300, bounds.Center().Y-80), atlas)
+	boardTxt.Color = colornames.Black
+	fmt.Fprintf(boardTxt, "Leaderboard %s\n", sr.run.Scenario)
+
+	// The board is sorted, so the first run of every player is their personal best
+	hasBest := make(map[string]bool)
+	newBest := false
+	for i, r := range sr.leaderboard {
+		personalBest := !hasBest[r.Player]
+		hasBest[r.Player] = true
+		isRun := r.Player == sr.run.Player && r.Date.Equal(sr.run.Date)
+		newBest = newBest || (isRun && personalBest)
+		if i >= maxLeaderboardLines && !isRun {
+			continue
+		}
+
+		marker := "  "
+		if isRun {
+			marker = "> "
+		}
+		boardTxt.Color = colornames.Black
+		if personalBest {
+			boardTxt.Color = colornames.Darkgreen
+		}
+		fmt.Fprintf(boardTxt, "%s%2d. %-12s %s %6.0f m %3d tacks", marker, i+1, r.Player, formatTicks(r.Ticks), r.Distance, r.Tacks)
+		if r.Flags > 0 {
+			fmt.Fprintf(boardTxt, " %d flags", r.Flags)
+		}
+		fmt.Fprintln(boardTxt)
+	}
+	if newBest {
+		boardTxt.Color = colornames.Darkgreen
+		fmt.Fprintln(boardTxt, "New personal best!")
+	}
+
+	boardTxt.Draw(win, pixel.IM.Scaled(boardTxt.Orig, 1.5))
+}
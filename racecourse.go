@@ -1,15 +1,31 @@
 package gosailing
 
 import (
+	"github.com/gopxl/pixel/v2"
 	"github.com/gopxl/pixel/v2/ext/imdraw"
 	"golang.org/x/image/colornames"
 )
 
+// CourseMark is a mark of a multi-leg course on the screen
+type CourseMark struct {
+	Name string
+	X    float64
+	Y    float64
+	// Rounding is the side of the boat that faces the mark, "port" or "starboard"
+	Rounding string
+	// IsGate is set for gates, the second mark of the gate is at GateX, GateY
+	IsGate bool
+	GateX  float64
+	GateY  float64
+}
+
 type RaceCourse struct {
 	MarkX             float64
 	MarkY             float64
+	marks             []CourseMark
 	windDirection     float64
 	laylines          bool
+	downwind          bool
 	showWindDirection bool
 	course            *imdraw.IMDraw
 }
@@ -34,6 +50,16 @@ func (rc *RaceCourse) SetWindDirection(direction float64) {
 	rc.windDirection = direction
 }
 
+// SetMarks sets all the marks of the course, the laylines are still drawn for MarkX, MarkY
+func (rc *RaceCourse) SetMarks(marks []CourseMark) {
+	rc.marks = marks
+}
+
+// SetDownwind draws the laylines to MarkX, MarkY for approaching it downwind
+func (rc *RaceCourse) SetDownwind(downwind bool) {
+	rc.downwind = downwind
+}
+
 func (rc *RaceCourse) ToggleLaylines() {
 	rc.laylines = !rc.laylines
 }
@@ -47,9 +73,32 @@ func (rc *RaceCourse) Drawable() *imdraw.IMDraw {
 
 	DrawFlag(rc.course, rc.MarkX, rc.MarkY)
 
+	for _, m := range rc.marks {
+		DrawFlag(rc.course, m.X, m.Y)
+		if m.IsGate {
+			DrawFlag(rc.course, m.GateX, m.GateY)
+			rc.course.Color = colornames.Orangered
+			rc.course.Push(pixel.V(m.X, m.Y), pixel.V(m.GateX, m.GateY))
+			rc.course.Line(1)
+			continue
+		}
+
+		// The rounding side is shown with the color of the side of the boat
+		rc.course.Color = colornames.Red
+		if m.Rounding == "starboard" {
+			rc.course.Color = colornames.Green
+		}
+		rc.course.Push(pixel.V(m.X, m.Y))
+		rc.course.Circle(8, 2)
+	}
+
 	if rc.laylines {
-		LayLine(rc.course, rc.MarkX, rc.MarkY, -TackAngle+rc.windDirection, colornames.Red)
-		LayLine(rc.course, rc.MarkX, rc.MarkY, TackAngle+rc.windDirection, colornames.Green)
+		angle := TackAngle
+		if rc.downwind {
+			angle = 180 - GybeAngle
+		}
+		LayLine(rc.course, rc.MarkX, rc.MarkY, -angle+rc.windDirection, colornames.Red)
+		LayLine(rc.course, rc.MarkX, rc.MarkY, angle+rc.windDirection, colornames.Green)
 	}
 
 	if rc.showWindDirection {
@@ -3,13 +3,17 @@ package gosailing
 import (
 	"errors"
 	"fmt"
+	"log"
 	"math"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/gopxl/pixel/v2"
 	"github.com/gopxl/pixel/v2/backends/opengl"
 	"github.com/gopxl/pixel/v2/ext/imdraw"
 	"github.com/gopxl/pixel/v2/ext/text"
+	"github.io/mpihlak/gosailing/analysis"
 	"github.io/mpihlak/gosailing/datasource"
 	"golang.org/x/image/colornames"
 	"golang.org/x/image/font/basicfont"
@@ -17,84 +21,462 @@ import (
 
 type RaceReplay struct {
 	raceCourse *RaceCourse
-	boat       *Boat
-	track      *TrackPlotter
-	replayData []replayDataPoint
-	xOffset    float64
-	yOffset    float64
-	currentPos int
-	delayMs    int
-	paused     bool
-	started    bool
-	finished   bool
-	laylines   bool
-	race       *imdraw.IMDraw
+	// boats of the fleet, the first one is our own boat
+	boats     []*replayBoat
+	timeline  *Timeline
+	scrubbing bool
+	// extraChannels are the names of the logged channels that are not mapped to fields
+	extraChannels []string
+	startTime     time.Time
+	endTime       time.Time
+	playTime      time.Time
+	lastFrame     time.Time
+	speedIndex    int
+	paused        bool
+	started       bool
+	finished      bool
+	laylines      bool
+	channels      bool
+	race          *imdraw.IMDraw
+	markLat       float64
+	markLng       float64
+	draggingMark  bool
+	// projection of the log locations to a plane in metres around the mark, the plane is
+	// rotated so that the median wind blows from the top and then mapped to the screen
+	projection datasource.LocalProjection
+	medianWind float64
+	screen     *ScreenTransform
+	camera     *Camera
+	// viewport is the part of the window that the session is fitted into, sessionArea is the
+	// part of the plane that the boats sailed
+	viewport    pixel.Rect
+	sessionArea pixel.Rect
+	following   bool
+	panning     bool
+	// branch is the simulated "what if" boat branched from our own boat
+	branch *replayBranch
+	// bookmarks are the notes of our own boat's log, note is the text of a new note being typed
+	bookmarks         datasource.Bookmarks
+	onBookmarksChange func(datasource.Bookmarks)
+	bookmarkList      bool
+	editingNote       bool
+	note              string
+	// charts are the strip charts of our own boat under the map, hoverTime is the time under
+	// the mouse on the charts or zero
+	charts      []*StripChart
+	showCharts  bool
+	hoverTime   time.Time
+	polar       *analysis.Polar
+	instruments []Instrument
+	// session are the legs and events detected from the log of our own boat
+	session analysis.SessionAnalysis
+	events  bool
+	losses  []analysis.ManeuverLoss
+	// course is the optional multi-leg course, the mark is then the target of the current leg.
+	// The leg targets are on the plane.
+	course     *datasource.Course
+	courseLegs []datasource.CourseLeg
+	legTargets []pixel.Vec
+	startBox   *StartingBox
+	finishBox  *StartingBox
 }
 
-type replayDataPoint struct {
-	datasource.NavigationDataPoint
-	x float64
-	y float64
-}
+// playbackSpeeds are the selectable multipliers of the log time
+var playbackSpeeds = []float64{1, 2, 10, 60}
+
+// frameDelay is the minimum time between frames
+const frameDelay = 10 * time.Millisecond
+
+// eventLeadTime is how much before an event the replay is positioned when jumping to it
+const eventLeadTime = 5 * time.Second
+
+// markGrabDistance is how close to the mark in pixels the mouse has to be pressed to drag it
+const markGrabDistance = 15.0
+
+// CourseRoundingRadius is how close in metres a boat has to come to a mark to round it
+const CourseRoundingRadius = 50.0
+
+// wheelZoomFactor is how much one step of the mouse wheel zooms
+const wheelZoomFactor = 1.2
+
+// Layout of the strip charts from the bottom up, above the timeline
+const (
+	chartsBottom = 55.0
+	chartHeight  = 28.0
+	chartGap     = 4.0
+)
+
+// maxScaleBarWidth is the maximum length of the scale bar in pixels
+const maxScaleBarWidth = 150.0
 
-func NewRaceReplay(markLat, markLng, maxWidth, maxHeight, zoomLevel float64, replayData *datasource.ReplayNavigationDataProvider) (*RaceReplay, error) {
-	navDataPoints := replayData.GetAllPoints()
-	if len(navDataPoints) == 0 {
-		return nil, errors.New("no navigation data points found")
+// maxEventLines is the number of events shown around the current one in the event list
+const maxEventLines = 20
+
+// NewRaceReplay creates a replay of the fleet. The boats are aligned by their log timestamps,
+// the first boat of the fleet is our own boat that the instruments are shown for. The scale is
+// in metres per pixel, with zero scale the whole session is fitted to the window.
+func NewRaceReplay(markLat, markLng, maxWidth, maxHeight, metersPerPixel float64, fleet []ReplayBoat) (*RaceReplay, error) {
+	if len(fleet) == 0 {
+		return nil, errors.New("no boats to replay")
 	}
 
-	medianWind := datasource.MedianWindDirection(navDataPoints)
+	fleetDataPoints := make([][]datasource.NavigationDataPoint, len(fleet))
+	var allDataPoints []datasource.NavigationDataPoint
+	for i, rb := range fleet {
+		if rb.Data.Len() == 0 {
+			return nil, fmt.Errorf("no navigation data points found for %s", rb.Name)
+		}
 
-	markX, markY := LatLngToScreen(markLat, markLng, zoomLevel)
+		navDataPoints := make([]datasource.NavigationDataPoint, rb.Data.Len())
+		for i := range navDataPoints {
+			navDataPoints[i] = rb.Data.At(i)
+		}
+		fleetDataPoints[i] = navDataPoints
+		allDataPoints = append(allDataPoints, navDataPoints...)
+	}
 
-	// Rotate the replay points to the median wind direction
-	replayDataPoints := make([]replayDataPoint, len(navDataPoints))
-	var minX, minY, maxX, maxY float64
-	for i, p := range navDataPoints {
-		x, y := LatLngToScreen(p.Latitude, p.Longitude, zoomLevel)
-		x, y = RotatePoint(x, y, markX, markY, -medianWind)
+	medianWind := datasource.MedianWindDirection(allDataPoints)
+
+	projection := datasource.NewLocalProjection(markLat, markLng)
+
+	// Rotate the replay points to the median wind direction around the mark
+	fleetReplayPoints := make([][]replayDataPoint, len(fleet))
+	var minY float64
+	for i, navDataPoints := range fleetDataPoints {
+		replayDataPoints := make([]replayDataPoint, len(navDataPoints))
+		for j, p := range navDataPoints {
+			x, y := projection.ToMeters(p.Latitude, p.Longitude)
+			x, y = RotatePoint(x, y, 0, 0, -medianWind)
+
+			replayDataPoints[j] = replayDataPoint{
+				NavigationDataPoint: p,
+				x:                   x,
+				y:                   y,
+				cog:                 p.CourseOverGround - medianWind,
+				twd:                 p.TrueWindDirection - medianWind,
+			}
 
-		replayDataPoints[i] = replayDataPoint{NavigationDataPoint: p, x: x, y: y}
-		replayDataPoints[i].CourseOverGround -= medianWind
-		replayDataPoints[i].TrueWindDirection -= medianWind
+			// Our own boat is kept on the screen
+			if i == 0 && (j == 0 || y < minY) {
+				minY = y
+			}
+		}
+		fleetReplayPoints[i] = replayDataPoints
+	}
 
-		if i == 0 || x < minX {
-			minX = x
+	// The session area is the bounding box of the rotated corners of the bounds and the mark
+	minLat, maxLat, minLng, maxLng := datasource.GetBounds(allDataPoints)
+	sessionArea := pixel.R(0, 0, 0, 0)
+	for _, corner := range [][2]float64{{minLat, minLng}, {minLat, maxLng}, {maxLat, minLng}, {maxLat, maxLng}} {
+		x, y := projection.ToMeters(corner[0], corner[1])
+		x, y = RotatePoint(x, y, 0, 0, -medianWind)
+		sessionArea = sessionArea.Union(pixel.R(x, y, x, y))
+	}
+
+	screen := &ScreenTransform{MetersPerPixel: metersPerPixel}
+	camera := NewCamera(screen)
+	viewport := pixel.R(40, 70, maxWidth-40, maxHeight-40)
+	if metersPerPixel > 0 {
+		// The mark is centered horizontally and the lowest point of our own boat is near the bottom
+		screen.OffsetX = maxWidth / 2
+		screen.OffsetY = 50 - minY/metersPerPixel
+	} else {
+		camera.Fit(sessionArea, viewport)
+	}
+
+	rr := &RaceReplay{
+		raceCourse:    NewRaceCourse(screen.OffsetX, screen.OffsetY, fleetReplayPoints[0][0].twd),
+		extraChannels: datasource.ExtraChannels(fleetDataPoints[0]),
+		speedIndex:    2,
+		laylines:      true,
+		markLat:       markLat,
+		markLng:       markLng,
+		projection:    projection,
+		medianWind:    medianWind,
+		screen:        screen,
+		camera:        camera,
+		viewport:      viewport,
+		sessionArea:   sessionArea,
+	}
+	rr.instruments, _ = ParseInstrumentPanel(DefaultInstrumentPanel)
+
+	for i, rb := range fleet {
+		boat := newReplayBoat(rb.Name, fleetColors[i%len(fleetColors)], fleetReplayPoints[i], screen)
+		// Laylines are only shown for our own boat to keep the screen readable
+		boat.boat.SetLaylines(i == 0)
+		rr.boats = append(rr.boats, boat)
+
+		if i == 0 || boat.startTime().Before(rr.startTime) {
+			rr.startTime = boat.startTime()
 		}
-		if i == 0 || y < minY {
-			minY = y
+		if i == 0 || boat.endTime().After(rr.endTime) {
+			rr.endTime = boat.endTime()
 		}
-		if i == 0 || x > maxX {
-			maxX = x
+	}
+
+	rr.playTime = rr.startTime
+	rr.timeline = NewTimeline(rr.startTime, rr.endTime, pixel.R(20, 15, maxWidth-20, 30))
+
+	for i, series := range ChartSeriesList {
+		y := chartsBottom + float64(len(ChartSeriesList)-1-i)*(chartHeight+chartGap)
+		bounds := pixel.R(20, y, maxWidth-20, y+chartHeight)
+		rr.charts = append(rr.charts, NewStripChart(series, fleetDataPoints[0], rr.startTime, rr.endTime, bounds))
+	}
+
+	rr.session = analysis.Analyze(fleetDataPoints[0], analysis.DefaultDetectionConfig())
+	markers := make([]time.Time, len(rr.session.Events))
+	for i, e := range rr.session.Events {
+		markers[i] = e.Timestamp
+	}
+	rr.timeline.SetMarkers(markers)
+	rr.updateLosses()
+
+	return rr, nil
+}
+
+// SetCourse sets the course that the boats sail. The marks and lines are drawn, and the
+// distances and the instruments are for the mark of the leg that our own boat is sailing.
+func (rr *RaceReplay) SetCourse(course *datasource.Course) {
+	rr.course = course
+	rr.courseLegs = course.Legs()
+	rr.legTargets = make([]pixel.Vec, len(rr.courseLegs))
+	for i, leg := range rr.courseLegs {
+		rr.legTargets[i] = rr.latLngToPlane(leg.To)
+	}
+
+	twd := rr.boats[0].current().twd
+	rr.startBox = NewStartingBox(0, 0, 0, 0, twd)
+	rr.startBox.SetShowWindDirection(false)
+	rr.startBox.SetLaylines(rr.laylines)
+	rr.finishBox = NewStartingBox(0, 0, 0, 0, twd)
+	rr.finishBox.SetShowWindDirection(false)
+	rr.finishBox.SetLaylines(false)
+	rr.layoutCourse()
+
+	for _, rb := range rr.boats {
+		points := make([]datasource.NavigationDataPoint, len(rb.data))
+		for i, p := range rb.data {
+			points[i] = p.NavigationDataPoint
+		}
+		for i, leg := range datasource.CourseProgress(points, course, CourseRoundingRadius) {
+			rb.data[i].leg = leg
 		}
-		if i == 0 || y > maxY {
-			maxY = y
+	}
+	rr.updateCourseLeg()
+}
+
+// layoutCourse places the marks and lines of the course on the screen
+func (rr *RaceReplay) layoutCourse() {
+	marks := make([]CourseMark, len(rr.course.Marks))
+	for i, m := range rr.course.Marks {
+		v := rr.latLngToScreen(m.LatLng)
+		marks[i] = CourseMark{Name: m.Name, X: v.X, Y: v.Y, Rounding: m.Rounding, IsGate: m.IsGate()}
+		if m.IsGate() {
+			gate := rr.latLngToScreen(*m.Gate)
+			marks[i].GateX, marks[i].GateY = gate.X, gate.Y
 		}
 	}
+	rr.raceCourse.SetMarks(marks)
 
-	xOffset := markX - maxWidth/2
-	yOffset := minY - 50
+	placeLine := func(box *StartingBox, line datasource.Line) {
+		boat, pin := rr.latLngToScreen(line.Boat), rr.latLngToScreen(line.Pin)
+		box.BoatEndX, box.BoatEndY = boat.X, boat.Y
+		box.PinEndX, box.PinEndY = pin.X, pin.Y
+	}
+	placeLine(rr.startBox, rr.course.Start)
+	placeLine(rr.finishBox, rr.course.FinishLine())
+}
+
+// layout places everything on the screen again after the camera has moved
+func (rr *RaceReplay) layout() {
+	mark := rr.latLngToScreen(datasource.LatLng{Latitude: rr.markLat, Longitude: rr.markLng})
+	rr.raceCourse.MarkX, rr.raceCourse.MarkY = mark.X, mark.Y
+	if rr.course != nil {
+		rr.layoutCourse()
+	}
+	for _, rb := range rr.boats {
+		rb.layout()
+	}
+	if rr.branch != nil {
+		rr.layoutBranch()
+	}
+}
 
-	p := replayDataPoints[0]
-	return &RaceReplay{
-		raceCourse: NewRaceCourse(markX-xOffset, markY-yOffset, p.TrueWindDirection),
-		replayData: replayDataPoints,
-		// TODO: Boat starts with incorrect sailed distance, fix this
-		boat:     NewBoat(p.x-xOffset, p.y-yOffset, p.TrueWindDirection),
-		track:    NewTrackPlotter(markX-xOffset, markY-yOffset),
-		delayMs:  50,
-		laylines: true,
-		xOffset:  xOffset,
-		yOffset:  yOffset,
-	}, nil
+// FitView zooms and centers the camera on the whole session
+func (rr *RaceReplay) FitView() {
+	rr.following = false
+	rr.camera.Fit(rr.sessionArea, rr.viewport)
+	rr.layout()
+}
+
+// ToggleFollow keeps our own boat in the center of the view
+func (rr *RaceReplay) ToggleFollow() {
+	rr.following = !rr.following
+}
+
+// follow centers the camera on our own boat
+func (rr *RaceReplay) follow() {
+	x, y, _, _ := rr.boats[0].interpolatedLocation(rr.playTime)
+	rr.camera.CenterOn(pixel.V(x, y), rr.viewport.Center())
+	rr.layout()
+}
+
+// updateCourseLeg moves the mark to the target of the leg that our own boat is sailing
+func (rr *RaceReplay) updateCourseLeg() {
+	if rr.course == nil {
+		return
+	}
+	leg := rr.boats[0].current().leg
+	rr.raceCourse.MarkX, rr.raceCourse.MarkY = rr.screen.ToScreen(rr.legTargets[leg].X, rr.legTargets[leg].Y)
+	rr.markLat, rr.markLng = rr.courseLegs[leg].To.Latitude, rr.courseLegs[leg].To.Longitude
+}
+
+// SetPolar sets the boat polar that the target speeds and laylines are computed from
+func (rr *RaceReplay) SetPolar(polar *analysis.Polar) {
+	rr.polar = polar
+	rr.updateLosses()
+}
+
+// updateLosses computes the maneuver losses of our own boat against the current polar
+func (rr *RaceReplay) updateLosses() {
+	own := rr.boats[0]
+	points := make([]datasource.NavigationDataPoint, len(own.data))
+	for i, p := range own.data {
+		points[i] = p.NavigationDataPoint
+	}
+	rr.losses = analysis.ManeuverLosses(points, rr.session.Events, rr.polar, analysis.DefaultLossConfig())
+}
+
+// maneuverAt returns the loss of the maneuver that is in progress at the time, or nil. The
+// maneuver lasts from a bit before the turn until the end of the loss window.
+func (rr *RaceReplay) maneuverAt(t time.Time) *analysis.ManeuverLoss {
+	lossWindow := analysis.DefaultLossConfig().LossWindow
+	for i, loss := range rr.losses {
+		from := loss.TurnStart
+		if loss.Event.Timestamp.Before(from) {
+			from = loss.Event.Timestamp
+		}
+		if !t.Before(from.Add(-eventLeadTime)) && !t.After(loss.TurnStart.Add(lossWindow)) {
+			return &rr.losses[i]
+		}
+	}
+	return nil
+}
+
+// SetInstrumentPanel sets the instruments that are shown for our own boat
+func (rr *RaceReplay) SetInstrumentPanel(instruments []Instrument) {
+	rr.instruments = instruments
 }
 
 func (rr *RaceReplay) StartReplay() {
-	rr.currentPos = 0
+	rr.SeekTime(rr.startTime)
+}
+
+// SeekTime moves the replay to the time. The tracks are plotted again from the start of the
+// session up to that point.
+func (rr *RaceReplay) SeekTime(t time.Time) {
+	if t.Before(rr.startTime) {
+		t = rr.startTime
+	}
+	if t.After(rr.endTime) {
+		t = rr.endTime
+	}
+
+	for _, rb := range rr.boats {
+		rb.seek(t)
+	}
+
+	// The simulated boat can't sail backwards in time
+	if rr.branch != nil && t.Before(rr.branch.sim.Time) {
+		rr.branch = nil
+	}
+
+	rr.playTime = t
 	rr.started = true
 	rr.finished = false
-	rr.track.Clear()
-	rr.boat.Reset()
+}
+
+// StepFrames moves the replay by n data points of our own boat, backwards if n is negative
+func (rr *RaceReplay) StepFrames(n int) {
+	own := rr.boats[0]
+	pos := min(len(own.data)-1, max(0, own.currentPos+n))
+	rr.SeekTime(own.data[pos].Timestamp)
+}
+
+// Skip moves the replay forward or backward in time by the duration
+func (rr *RaceReplay) Skip(d time.Duration) {
+	rr.SeekTime(rr.playTime.Add(d))
+}
+
+// HandleMouse seeks the replay when the timeline is clicked or dragged, moves the mark when it
+// is dragged, pans the view when anything else is dragged and zooms with the mouse wheel
+func (rr *RaceReplay) HandleMouse(win *opengl.Window) {
+	mouse := win.MousePosition()
+	if win.JustPressed(pixel.MouseButtonLeft) {
+		markX, markY := rr.raceCourse.MarkX, rr.raceCourse.MarkY
+		switch {
+		case rr.timeline.Contains(mouse), rr.chartAt(mouse) != nil:
+			rr.scrubbing = true
+		case rr.course == nil && math.Hypot(mouse.X-markX, mouse.Y-markY) < markGrabDistance:
+			rr.draggingMark = true
+		default:
+			rr.panning = true
+			rr.following = false
+		}
+	}
+	if !win.Pressed(pixel.MouseButtonLeft) {
+		if rr.draggingMark {
+			log.Printf("Mark moved to -markLat %.6f -markLng %.6f", rr.markLat, rr.markLng)
+		}
+		rr.scrubbing = false
+		rr.draggingMark = false
+		rr.panning = false
+	}
+	if rr.panning {
+		if delta := mouse.Sub(win.MousePreviousPosition()); delta.Len() > 0 {
+			rr.camera.Pan(delta)
+			rr.layout()
+		}
+	}
+	if scroll := win.MouseScroll().Y; scroll != 0 {
+		rr.camera.Zoom(mouse, math.Pow(wheelZoomFactor, scroll))
+		rr.layout()
+	}
+	rr.hoverTime = time.Time{}
+	if chart := rr.chartAt(mouse); chart != nil {
+		rr.hoverTime = chart.TimeAt(mouse.X)
+	}
+	if rr.scrubbing {
+		rr.SeekTime(rr.timeline.TimeAt(mouse.X))
+	}
+	if rr.draggingMark {
+		rr.raceCourse.MarkX, rr.raceCourse.MarkY = mouse.X, mouse.Y
+		rr.markLat, rr.markLng = rr.screenToLatLng(mouse)
+	}
+}
+
+// MarkLocation returns the latitude and longitude of the mark
+func (rr *RaceReplay) MarkLocation() (float64, float64) {
+	return rr.markLat, rr.markLng
+}
+
+// latLngToPlane projects a location to the plane the same way as the logged points
+func (rr *RaceReplay) latLngToPlane(l datasource.LatLng) pixel.Vec {
+	x, y := rr.projection.ToMeters(l.Latitude, l.Longitude)
+	return pixel.V(RotatePoint(x, y, 0, 0, -rr.medianWind))
+}
+
+// latLngToScreen projects a location to the screen
+func (rr *RaceReplay) latLngToScreen(l datasource.LatLng) pixel.Vec {
+	v := rr.latLngToPlane(l)
+	return pixel.V(rr.screen.ToScreen(v.X, v.Y))
+}
+
+// screenToLatLng converts a location on the screen back to latitude and longitude
+func (rr *RaceReplay) screenToLatLng(v pixel.Vec) (float64, float64) {
+	x, y := rr.screen.ToPlane(v.X, v.Y)
+	x, y = RotatePoint(x, y, 0, 0, rr.medianWind)
+	return rr.projection.ToLatLng(x, y)
 }
 
 func (rr *RaceReplay) IsFinished() bool {
@@ -102,11 +484,25 @@ func (rr *RaceReplay) IsFinished() bool {
 }
 
 func (rr *RaceReplay) IncreaseSpeed() {
-	rr.delayMs = max(0, rr.delayMs-10)
+	rr.speedIndex = min(len(playbackSpeeds)-1, rr.speedIndex+1)
 }
 
 func (rr *RaceReplay) DecreaseSpeed() {
-	rr.delayMs += 10
+	rr.speedIndex = max(0, rr.speedIndex-1)
+}
+
+// advance moves the playback time forward by the log time that corresponds to the wall
+// clock duration, passing through all the data points on the way
+func (rr *RaceReplay) advance(wallClock time.Duration) {
+	rr.playTime = rr.playTime.Add(time.Duration(float64(wallClock) * playbackSpeeds[rr.speedIndex]))
+	if !rr.playTime.Before(rr.endTime) {
+		rr.playTime = rr.endTime
+		rr.finished = true
+	}
+
+	for _, rb := range rr.boats {
+		rb.advance(rr.playTime)
+	}
 }
 
 func (rr *RaceReplay) TogglePause() {
@@ -118,16 +514,94 @@ func (rr *RaceReplay) TogglePause() {
 }
 
 func (rr *RaceReplay) ToggleLaylines() {
-	rr.boat.ToggleLaylines()
+	rr.laylines = !rr.laylines
+	rr.boats[0].boat.ToggleLaylines()
 	rr.raceCourse.ToggleLaylines()
+	if rr.startBox != nil {
+		rr.startBox.ToggleLaylines()
+	}
 }
 
 func (rr *RaceReplay) ToggleWindDirection() {
 	rr.raceCourse.ToggleWindDirection()
 }
 
+// ToggleChannels shows or hides the extra channels that the logger recorded
+func (rr *RaceReplay) ToggleChannels() {
+	rr.channels = !rr.channels
+	rr.events = false
+	rr.bookmarkList = false
+}
+
+// ToggleCharts shows or hides the strip charts under the map. The map is fitted above the
+// charts from then on.
+func (rr *RaceReplay) ToggleCharts() {
+	rr.showCharts = !rr.showCharts
+	rr.viewport.Min.Y = rr.mapBottom() + 20
+}
+
+// mapBottom returns the screen Y coordinate that the map starts from
+func (rr *RaceReplay) mapBottom() float64 {
+	if rr.showCharts {
+		return rr.charts[0].Bounds().Max.Y
+	}
+	return rr.timeline.Bounds().Max.Y + 20
+}
+
+// chartAt returns the strip chart at the screen position, or nil if there isn't one
+func (rr *RaceReplay) chartAt(pos pixel.Vec) *StripChart {
+	if !rr.showCharts {
+		return nil
+	}
+	for _, chart := range rr.charts {
+		if chart.Contains(pos) {
+			return chart
+		}
+	}
+	return nil
+}
+
+// ToggleEvents shows or hides the list of the detected events, in place of the channels
+func (rr *RaceReplay) ToggleEvents() {
+	rr.events = !rr.events
+	rr.channels = false
+	rr.bookmarkList = false
+}
+
+// Events returns the legs and events detected from the log of our own boat
+func (rr *RaceReplay) Events() analysis.SessionAnalysis {
+	return rr.session
+}
+
+// JumpToEvent seeks the replay to the detected event, a few seconds before it happens
+func (rr *RaceReplay) JumpToEvent(i int) {
+	if i < 0 || i >= len(rr.session.Events) {
+		return
+	}
+	rr.SeekTime(rr.session.Events[i].Timestamp.Add(-eventLeadTime))
+}
+
+// NextEvent jumps to the first event after the current one
+func (rr *RaceReplay) NextEvent() {
+	rr.JumpToEvent(rr.currentEvent() + 1)
+}
+
+// PreviousEvent jumps to the event before the current one
+func (rr *RaceReplay) PreviousEvent() {
+	rr.JumpToEvent(rr.currentEvent() - 1)
+}
+
+// currentEvent returns the index of the last event that the replay has reached, counting the
+// event that was jumped to as reached. Returns -1 before the first event.
+func (rr *RaceReplay) currentEvent() int {
+	reached := rr.playTime.Add(eventLeadTime)
+	return sort.Search(len(rr.session.Events), func(i int) bool {
+		return rr.session.Events[i].Timestamp.After(reached)
+	}) - 1
+}
+
 func (rr *RaceReplay) Throttle() {
-	time.Sleep(time.Duration(rr.delayMs) * time.Millisecond)
+	time.Sleep(frameDelay)
 }
 
 func (rr *RaceReplay) Update(win *opengl.Window) {
@@ -146,8 +620,21 @@ func (rr *RaceReplay) Update(win *opengl.Window) {
 			"'r' restarts'",
 			"'l' toggle laylines",
 			"'w' toggle wind",
-			"'1' increases speed'",
-			"'2' decreases speed'",
+			"'c' toggle all channels",
+			"'e' toggle events, '[' ']' jump to events",
+			"',' '.' step one frame",
+			"LEFT RIGHT skip 10s, DOWN UP skip 60s",
+			"click or drag the timeline to seek",
+			"drag the mark to move it",
+			"mouse wheel zooms, drag to pan",
+			"'f' follow our boat, 'z' fit the session",
+			"'b' branch a what if boat when paused",
+			"'t' tack it, 'a' auto tack on headers",
+			"'g' toggle strip charts",
+			"'m' bookmark with a note, 'k' list bookmarks",
+			"PGUP PGDN jump to bookmarks, DEL removes one",
+			"'1' increases speed (1x 2x 10x 60x)",
+			"'2' decreases speed",
 		}
 
 		basicTxt := text.New(pixel.V(textX, textY), basicAtlas)
@@ -157,45 +644,89 @@ func (rr *RaceReplay) Update(win *opengl.Window) {
 		}
 		basicTxt.Draw(win, pixel.IM.Scaled(basicTxt.Orig, 2))
 	} else {
-		navData := rr.replayData[rr.currentPos]
-		if !rr.paused {
-			if rr.currentPos < len(rr.replayData)-1 {
-				rr.currentPos++
-			} else {
-				rr.finished = true
-			}
+		now := time.Now()
+		if !rr.paused && !rr.finished && !rr.lastFrame.IsZero() {
+			rr.advance(now.Sub(rr.lastFrame))
 		}
+		rr.lastFrame = now
 
-		rr.boat.SetLocation(navData.x-rr.xOffset, navData.y-rr.yOffset, navData.CourseOverGround, navData.TrueWindDirection)
-		rr.track.PlotLocation(rr.boat.GetXY())
-		rr.raceCourse.SetWindDirection(navData.TrueWindDirection)
+		if rr.following {
+			rr.follow()
+		}
+		for _, rb := range rr.boats {
+			rb.update(rr.playTime)
+		}
+		if rr.branch != nil {
+			rr.updateBranch()
+		}
+
+		own := rr.boats[0]
+		navData := own.current()
+		rr.raceCourse.SetWindDirection(own.boat.windDirection)
+		rr.updateCourseLeg()
 
 		basicTxt := text.New(pixel.V(10, topLeftY-25), basicAtlas)
 		basicTxt.Color = colornames.Black
 
-		currentBoatX, currentBoatY := rr.boat.GetXY()
-		distanceToMark := math.Hypot(currentBoatX-rr.raceCourse.MarkX, currentBoatY-rr.raceCourse.MarkY)
-		fmt.Fprintf(basicTxt, "Sailed distance:  %.2f\n", rr.boat.GetSailedDistance())
-		fmt.Fprintf(basicTxt, "Distance to mark: %.2f\n", distanceToMark)
-
-		hdg := -rr.boat.heading
-		if hdg < 0 {
-			hdg += 360
-		}
-		fmt.Fprintf(basicTxt, "HDG: %03.0f\n", hdg)
-		twd := -rr.raceCourse.windDirection
-		if twd < 0 {
-			twd = 360 + twd
-		}
-		fmt.Fprintf(basicTxt, "TWD: %03.0f\n", twd)
-		fmt.Fprintf(basicTxt, "TWA: %03.0f\n", navData.TrueWindAngle)
-		fmt.Fprintf(basicTxt, "TWS: %03.0f\n", navData.TrueWindSpeed)
-		fmt.Fprintf(basicTxt, "AWS: %03.0f\n", navData.ApparentWindSpeed)
-		fmt.Fprintf(basicTxt, "AWA: %03.0f\n", navData.ApparentWindAngle)
-		fmt.Fprintf(basicTxt, "SOG: %03.2f\n", navData.SpeedOverGround)
-		fmt.Fprintf(basicTxt, "STW: %03.2f\n", navData.SpeedThroughWater)
+		distanceToMark := rr.distanceToMark(own)
+		sailedDistance := own.sailedDistance(rr.playTime)
+		fmt.Fprintf(basicTxt, "Log time:   %s (%gx)\n", rr.playTime.Format("15:04:05"), playbackSpeeds[rr.speedIndex])
+		fmt.Fprintf(basicTxt, "Race clock: %s\n", formatClock(rr.playTime.Sub(rr.startTime)))
+		fmt.Fprintf(basicTxt, "Sailed distance:  %s\n", formatDistance(sailedDistance))
+		fmt.Fprintf(basicTxt, "Distance to mark: %s\n", formatDistance(distanceToMark))
+		if rr.course != nil {
+			leg := rr.courseLegs[navData.leg]
+			fmt.Fprintf(basicTxt, "Leg %d/%d %s: %s\n", navData.leg+1, len(rr.courseLegs), leg.Name,
+				formatDistance(leg.Distance*datasource.MetersPerNauticalMile))
+		}
+
+		instrumentData := InstrumentData{
+			Point:       navData.NavigationDataPoint,
+			Performance: analysis.ComputePerformance(navData.NavigationDataPoint, rr.markLat, rr.markLng, rr.polar),
+		}
+		if rr.course != nil {
+			instrumentData.StartLine = &rr.course.Start
+		}
+		for _, instrument := range rr.instruments {
+			fmt.Fprintf(basicTxt, "%s: %s\n", instrument.Label, instrument.Value(instrumentData))
+		}
+		if rr.branch != nil {
+			rr.printBranch(basicTxt)
+		}
 		basicTxt.Draw(win, pixel.IM.Scaled(basicTxt.Orig, 2))
 
+		if rr.channels {
+			channelsTxt := text.New(pixel.V(windowBounds.W()-250, topLeftY-25), basicAtlas)
+			channelsTxt.Color = colornames.Black
+			if navData.HasWaterTemperature {
+				fmt.Fprintf(channelsTxt, "MTW:   %.1f\n", navData.WaterTemperature)
+			} else {
+				fmt.Fprintf(channelsTxt, "MTW:   -\n")
+			}
+			fmt.Fprintf(channelsTxt, "ROT:   %.1f\n", navData.RateOfTurn)
+			fmt.Fprintf(channelsTxt, "PITCH: %.1f\n", navData.Pitch)
+			for _, name := range rr.extraChannels {
+				if v, ok := navData.Extra[name]; ok {
+					fmt.Fprintf(channelsTxt, "%s: %.2f\n", strings.ToUpper(name), v)
+				}
+			}
+			channelsTxt.Draw(win, pixel.IM.Scaled(channelsTxt.Orig, 2))
+		}
+
+		if rr.events {
+			rr.drawEvents(win, basicAtlas)
+		}
+		if rr.bookmarkList {
+			rr.drawBookmarks(win, basicAtlas)
+		}
+		rr.drawNote(win, basicAtlas)
+
+		if rr.paused {
+			if loss := rr.maneuverAt(rr.playTime); loss != nil {
+				rr.drawManeuverLoss(win, basicAtlas, loss)
+			}
+		}
+
 		if rr.paused && !rr.finished {
 			textX := windowBounds.Center().X
 			textY := windowBounds.Center().Y
@@ -215,12 +746,247 @@ func (rr *RaceReplay) Update(win *opengl.Window) {
 			basicTxt := text.New(pixel.V(textX, textY), basicAtlas)
 
 			basicTxt.Color = colornames.Darkblue
-			fmt.Fprintf(basicTxt, "TOTAL DISTANCE: %.2f\n", rr.boat.GetSailedDistance()+distanceToMark)
+			fmt.Fprintf(basicTxt, "TOTAL DISTANCE: %s\n", formatDistance(sailedDistance+distanceToMark))
 			basicTxt.Draw(win, pixel.IM.Scaled(basicTxt.Orig, 2))
 		}
 
-		rr.boat.Drawable().Draw(win)
 		rr.raceCourse.Drawable().Draw(win)
-		rr.track.Drawable().Draw(win)
+		if rr.course != nil {
+			rr.startBox.SetWindDirection(own.boat.windDirection)
+			rr.startBox.Drawable().Draw(win)
+			rr.finishBox.Drawable().Draw(win)
+			rr.drawMarkNames(win, basicAtlas)
+		}
+		if rr.branch != nil {
+			rr.drawTrack(win, rr.branch.track, rr.branch.plotted)
+			rr.branch.boat.Drawable().Draw(win)
+		}
+		for i := len(rr.boats) - 1; i >= 0; i-- {
+			rr.drawTrack(win, rr.boats[i].track, rr.boats[i].plotted)
+			rr.boats[i].boat.Drawable().Draw(win)
+		}
+		if len(rr.boats) > 1 {
+			rr.drawFleet(win, basicAtlas)
+		}
+
+		rr.timeline.Drawable(rr.playTime).Draw(win)
+		rr.drawTimelineLabels(win, basicAtlas, rr.playTime)
+		if rr.showCharts {
+			rr.drawCharts(win, basicAtlas)
+		}
+		rr.drawScaleBar(win, basicAtlas)
+	}
+}
+
+// drawTrack draws the track that was plotted with the screen transform where the camera shows it now
+func (rr *RaceReplay) drawTrack(win *opengl.Window, track *TrackPlotter, plotted ScreenTransform) {
+	win.SetMatrix(rr.screen.Matrix(plotted))
+	track.Drawable().Draw(win)
+	win.SetMatrix(pixel.IM)
+}
+
+// distanceToMark returns the distance in metres of the boat to the mark of the leg that it is
+// sailing
+func (rr *RaceReplay) distanceToMark(rb *replayBoat) float64 {
+	x, y, _, _ := rb.interpolatedLocation(rr.playTime)
+	target := rr.latLngToPlane(datasource.LatLng{Latitude: rr.markLat, Longitude: rr.markLng})
+	if rr.course != nil {
+		target = rr.legTargets[rb.current().leg]
+	}
+	return math.Hypot(x-target.X, y-target.Y)
+}
+
+// drawMarkNames labels the marks of the course
+func (rr *RaceReplay) drawMarkNames(win *opengl.Window, atlas *text.Atlas) {
+	for i, m := range rr.course.Marks {
+		x, y := rr.screen.ToScreen(rr.legTargets[i].X, rr.legTargets[i].Y)
+		label := text.New(pixel.V(x+12, y-15), atlas)
+		label.Color = colornames.Black
+		fmt.Fprint(label, m.Name)
+		label.Draw(win, pixel.IM)
+	}
+}
+
+// drawManeuverLoss draws a pop-up with the loss report of the maneuver
+func (rr *RaceReplay) drawManeuverLoss(win *opengl.Window, atlas *text.Atlas, loss *analysis.ManeuverLoss) {
+	center := win.Bounds().Center()
+
+	reportTxt := text.New(pixel.V(center.X-170, center.Y-60), atlas)
+	reportTxt.Color = colornames.Black
+	fmt.Fprintf(reportTxt, "%s at %s\n", strings.ToUpper(loss.Event.Type.String()), loss.Event.Timestamp.Format("15:04:05"))
+	fmt.Fprintf(reportTxt, "Entry speed:  %.2f kn\n", loss.EntrySpeed)
+	fmt.Fprintf(reportTxt, "Min speed:    %.2f kn\n", loss.MinSpeed)
+	fmt.Fprintf(reportTxt, "Exit speed:   %.2f kn\n", loss.ExitSpeed)
+	fmt.Fprintf(reportTxt, "Turn:         %.0f s\n", loss.TurnDuration().Seconds())
+	if loss.Recovered {
+		fmt.Fprintf(reportTxt, "Recovery:     %.0f s\n", loss.RecoveryTime.Seconds())
+	} else {
+		fmt.Fprintln(reportTxt, "Recovery:     not recovered")
+	}
+	fmt.Fprintf(reportTxt, "Lost:         %.1f m\n", loss.MetersLost)
+
+	scale := 1.5
+	bounds := reportTxt.Bounds()
+	background := imdraw.New(nil)
+	background.Color = colornames.White
+	bottomLeft := reportTxt.Orig.Add(bounds.Min.Sub(reportTxt.Orig).Scaled(scale)).Sub(pixel.V(10, 10))
+	topRight := reportTxt.Orig.Add(bounds.Max.Sub(reportTxt.Orig).Scaled(scale)).Add(pixel.V(10, 10))
+	background.Push(bottomLeft, topRight)
+	background.Rectangle(0)
+	background.Color = colornames.Gray
+	background.Push(bottomLeft, topRight)
+	background.Rectangle(1)
+
+	background.Draw(win)
+	reportTxt.Draw(win, pixel.IM.Scaled(reportTxt.Orig, scale))
+}
+
+// drawEvents draws the list of the detected events around the current one
+func (rr *RaceReplay) drawEvents(win *opengl.Window, atlas *text.Atlas) {
+	bounds := win.Bounds()
+	eventsTxt := text.New(pixel.V(bounds.W()-250, bounds.H()-25), atlas)
+	eventsTxt.Color = colornames.Black
+
+	events := rr.session.Events
+	if len(events) == 0 {
+		fmt.Fprintln(eventsTxt, "No events")
+	}
+
+	current := rr.currentEvent()
+	first := max(0, min(current-maxEventLines/2, len(events)-maxEventLines))
+	for i := first; i < len(events) && i < first+maxEventLines; i++ {
+		eventsTxt.Color = colornames.Black
+		if i == current {
+			eventsTxt.Color = colornames.Orangered
+		}
+		fmt.Fprintf(eventsTxt, "%s %s\n", events[i].Timestamp.Format("15:04:05"), events[i].Type)
+	}
+	eventsTxt.Draw(win, pixel.IM.Scaled(eventsTxt.Orig, 1.5))
+}
+
+// drawFleet draws the boat name labels and the ranking of the boats by distance to the mark
+func (rr *RaceReplay) drawFleet(win *opengl.Window, atlas *text.Atlas) {
+	for _, rb := range rr.boats {
+		x, y := rb.boat.GetXY()
+		label := text.New(pixel.V(x+10, y+5), atlas)
+		label.Color = rb.color
+		fmt.Fprint(label, rb.name)
+		label.Draw(win, pixel.IM)
+	}
+
+	ranking := make([]*replayBoat, len(rr.boats))
+	copy(ranking, rr.boats)
+	// With a course the boats further along the course are ahead
+	sort.SliceStable(ranking, func(i, j int) bool {
+		legI, legJ := ranking[i].current().leg, ranking[j].current().leg
+		if legI != legJ {
+			return legI > legJ
+		}
+		return rr.distanceToMark(ranking[i]) < rr.distanceToMark(ranking[j])
+	})
+
+	bounds := win.Bounds()
+	table := text.New(pixel.V(bounds.W()-250, rr.mapBottom()+10+float64(len(ranking))*26), atlas)
+	table.Color = colornames.Black
+	fmt.Fprintln(table, "To mark:")
+	for i, rb := range ranking {
+		table.Color = rb.color
+		fmt.Fprintf(table, "%d. %-10s %8s\n", i+1, rb.name, formatDistance(rr.distanceToMark(rb)))
+	}
+	table.Draw(win, pixel.IM.Scaled(table.Orig, 2))
+}
+
+func (rr *RaceReplay) drawTimelineLabels(win *opengl.Window, atlas *text.Atlas, current time.Time) {
+	bounds := rr.timeline.Bounds()
+	first := rr.startTime
+	last := rr.endTime
+
+	labels := text.New(pixel.V(bounds.Min.X, bounds.Max.Y+6), atlas)
+	labels.Color = colornames.Black
+	fmt.Fprint(labels, first.Format("15:04:05"))
+
+	labels.Dot.X = rr.timeline.XAt(current) - labels.BoundsOf("15:04:05").W()/2
+	fmt.Fprint(labels, current.Format("15:04:05"))
+
+	labels.Dot.X = bounds.Max.X - labels.BoundsOf("15:04:05").W()
+	fmt.Fprint(labels, last.Format("15:04:05"))
+
+	labels.Draw(win, pixel.IM)
+}
+
+// drawCharts draws the strip charts with the values at the hover time, or at the playback
+// time when the mouse isn't on the charts
+func (rr *RaceReplay) drawCharts(win *opengl.Window, atlas *text.Atlas) {
+	readoutTime := rr.playTime
+	if !rr.hoverTime.IsZero() {
+		readoutTime = rr.hoverTime
+	}
+
+	for _, chart := range rr.charts {
+		chart.Drawable(rr.playTime, rr.hoverTime).Draw(win)
+
+		bounds := chart.Bounds()
+		label := text.New(pixel.V(bounds.Min.X+5, bounds.Max.Y-12), atlas)
+		label.Color = colornames.Black
+		fmt.Fprintf(label, "%s "+chart.Format(), chart.Label(), chart.ValueAt(readoutTime))
+		label.Draw(win, pixel.IM)
+	}
+
+	if !rr.hoverTime.IsZero() {
+		top := rr.charts[0].Bounds().Max.Y
+		label := text.New(pixel.V(rr.timeline.XAt(rr.hoverTime)+4, top+4), atlas)
+		label.Color = colornames.Black
+		fmt.Fprint(label, rr.hoverTime.Format("15:04:05"))
+		label.Draw(win, pixel.IM)
+	}
+}
+
+// drawScaleBar draws a bar of a round length above the timeline or the charts
+func (rr *RaceReplay) drawScaleBar(win *opengl.Window, atlas *text.Atlas) {
+	length := scaleBarLength(rr.screen.MetersPerPixel, maxScaleBarWidth)
+	width := length / rr.screen.MetersPerPixel
+	x, y := 20.0, rr.mapBottom()+10
+
+	bar := imdraw.New(nil)
+	bar.Color = colornames.Black
+	bar.Push(pixel.V(x, y+5), pixel.V(x, y), pixel.V(x+width, y), pixel.V(x+width, y+5))
+	bar.Line(2)
+	bar.Draw(win)
+
+	label := text.New(pixel.V(x+width+8, y-3), atlas)
+	label.Color = colornames.Black
+	fmt.Fprint(label, formatDistance(length))
+	label.Draw(win, pixel.IM)
+}
+
+// scaleBarLength returns the longest length of 1, 2 or 5 times a power of ten metres that fits
+// in the number of pixels
+func scaleBarLength(metersPerPixel, maxPixels float64) float64 {
+	maxMeters := metersPerPixel * maxPixels
+	length := math.Pow(10, math.Floor(math.Log10(maxMeters)))
+	for _, multiple := range []float64{5, 2} {
+		if length*multiple <= maxMeters {
+			return length * multiple
+		}
+	}
+	return length
+}
+
+// formatDistance formats a distance in metres, switching to nautical miles from one mile up
+func formatDistance(meters float64) string {
+	if math.Abs(meters) < datasource.MetersPerNauticalMile {
+		return fmt.Sprintf("%.0f m", meters)
+	}
+	return fmt.Sprintf("%.2f nm", meters/datasource.MetersPerNauticalMile)
+}
+
+// formatClock formats the duration as h:mm:ss
+func formatClock(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
 	}
+	d = d.Truncate(time.Second)
+	return fmt.Sprintf("%s%d:%02d:%02d", sign, int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
 }
@@ -0,0 +1,26 @@
+package gosailing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScaleBarLength(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal(1000.0, scaleBarLength(7, 150))
+	require.Equal(500.0, scaleBarLength(4, 150))
+	require.Equal(200.0, scaleBarLength(2, 150))
+	require.Equal(100.0, scaleBarLength(1, 150))
+	require.Equal(10.0, scaleBarLength(0.1, 150))
+}
+
+func TestFormatDistance(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal("0 m", formatDistance(0))
+	require.Equal("850 m", formatDistance(849.6))
+	require.Equal("1.00 nm", formatDistance(1852))
+	require.Equal("2.50 nm", formatDistance(4630))
+}
@@ -0,0 +1,171 @@
+package gosailing
+
+import (
+	"image/color"
+	"math"
+	"sort"
+	"time"
+
+	"github.io/mpihlak/gosailing/datasource"
+	"golang.org/x/image/colornames"
+)
+
+// ReplayBoat is the recorded data of one boat of the replayed fleet
+type ReplayBoat struct {
+	Name string
+	Data datasource.SeekableNavigationDataProvider
+}
+
+// fleetColors are used for the boats of the fleet in order, the first one is our own boat
+var fleetColors = []color.RGBA{
+	colornames.Darkblue,
+	colornames.Darkorange,
+	colornames.Purple,
+	colornames.Teal,
+	colornames.Saddlebrown,
+	colornames.Deeppink,
+}
+
+// replayDataPoint is a logged point with its location on the local plane in metres, rotated so
+// that the median wind blows from the top. The course and wind direction are rotated along with
+// the location, the logged values are kept unchanged.
+type replayDataPoint struct {
+	datasource.NavigationDataPoint
+	x   float64
+	y   float64
+	cog float64
+	twd float64
+	// distance is the distance in metres sailed from the first point of the boat
+	distance float64
+	// leg is the index of the course leg that the boat is sailing, zero without a course
+	leg int
+}
+
+// replayBoat is the playback state of one boat of the fleet
+type replayBoat struct {
+	name       string
+	color      color.RGBA
+	data       []replayDataPoint
+	boat       *Boat
+	track      *TrackPlotter
+	currentPos int
+	screen     *ScreenTransform
+	// plotted is the screen transform that the track was plotted with, the track is moved to
+	// where the camera is when it is drawn
+	plotted ScreenTransform
+}
+
+func newReplayBoat(name string, color color.RGBA, data []replayDataPoint, screen *ScreenTransform) *replayBoat {
+	for i := 1; i < len(data); i++ {
+		data[i].distance = data[i-1].distance + math.Hypot(data[i].x-data[i-1].x, data[i].y-data[i-1].y)
+	}
+
+	p := data[0]
+	x, y := screen.ToScreen(p.x, p.y)
+	boat := NewBoat(x, y, p.twd)
+	boat.SetColor(color)
+	track := NewTrackPlotter(x, y)
+	track.SetColor(color)
+
+	return &replayBoat{
+		name:    name,
+		color:   color,
+		data:    data,
+		boat:    boat,
+		track:   track,
+		screen:  screen,
+		plotted: *screen,
+	}
+}
+
+// seek positions the boat at the last data point at or before the time and plots the track from
+// the start of the boat's data up to that point
+func (rb *replayBoat) seek(t time.Time) {
+	pos := sort.Search(len(rb.data), func(i int) bool {
+		return rb.data[i].Timestamp.After(t)
+	}) - 1
+	rb.currentPos = max(0, pos)
+
+	p := rb.data[rb.currentPos]
+	x, y := rb.screen.ToScreen(p.x, p.y)
+	rb.boat.ResetLocation(x, y, p.cog, p.twd)
+	rb.replot()
+}
+
+// replot plots the track again with the current screen transform
+func (rb *replayBoat) replot() {
+	rb.plotted = *rb.screen
+	rb.track.Restart(rb.plotted.ToScreen(rb.data[0].x, rb.data[0].y))
+	for _, p := range rb.data[1 : rb.currentPos+1] {
+		rb.track.PlotLocation(rb.plotted.ToScreen(p.x, p.y))
+	}
+}
+
+// layout replots the track after the camera has zoomed, panning only moves the plotted track
+func (rb *replayBoat) layout() {
+	if rb.plotted.MetersPerPixel != rb.screen.MetersPerPixel {
+		rb.replot()
+	}
+}
+
+// advance moves the boat through all the data points up to the time
+func (rb *replayBoat) advance(t time.Time) {
+	for rb.currentPos < len(rb.data)-1 && !rb.data[rb.currentPos+1].Timestamp.After(t) {
+		rb.currentPos++
+		p := rb.data[rb.currentPos]
+		rb.moveTo(p.x, p.y, p.cog, p.twd)
+	}
+}
+
+// update places the boat at the location interpolated for the time
+func (rb *replayBoat) update(t time.Time) {
+	x, y, cog, twd := rb.interpolatedLocation(t)
+	rb.moveTo(x, y, cog, twd)
+}
+
+func (rb *replayBoat) moveTo(x, y, cog, twd float64) {
+	rb.track.PlotLocation(rb.plotted.ToScreen(x, y))
+	x, y = rb.screen.ToScreen(x, y)
+	rb.boat.SetLocation(x, y, cog, twd)
+}
+
+// current returns the data point at or before the playback time
+func (rb *replayBoat) current() replayDataPoint {
+	return rb.data[rb.currentPos]
+}
+
+// sailedDistance returns the distance in metres that the boat has sailed by the time
+func (rb *replayBoat) sailedDistance(t time.Time) float64 {
+	x, y, _, _ := rb.interpolatedLocation(t)
+	p := rb.data[rb.currentPos]
+	return p.distance + math.Hypot(x-p.x, y-p.y)
+}
+
+func (rb *replayBoat) startTime() time.Time {
+	return rb.data[0].Timestamp
+}
+
+func (rb *replayBoat) endTime() time.Time {
+	return rb.data[len(rb.data)-1].Timestamp
+}
+
+// interpolatedLocation returns the boat location on the plane, course and wind direction at the time,
+// interpolated between the current and next data points
+func (rb *replayBoat) interpolatedLocation(t time.Time) (x, y, cog, twd float64) {
+	p := rb.data[rb.currentPos]
+	if rb.currentPos == len(rb.data)-1 {
+		return p.x, p.y, p.cog, p.twd
+	}
+
+	next := rb.data[rb.currentPos+1]
+	f := 0.0
+	if interval := next.Timestamp.Sub(p.Timestamp); interval > 0 {
+		f = min(1, max(0, float64(t.Sub(p.Timestamp))/float64(interval)))
+	}
+
+	x = p.x + (next.x-p.x)*f
+	y = p.y + (next.y-p.y)*f
+	cog = p.cog + datasource.NormalizeAngle(next.cog-p.cog)*f
+	twd = p.twd + datasource.NormalizeAngle(next.twd-p.twd)*f
+	return x, y, cog, twd
+}
@@ -0,0 +1,78 @@
+package gosailing
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+)
+
+// RaceResult is a finished run of the game
+type RaceResult struct {
+	Player   string    `json:"player"`
+	Scenario string    `json:"scenario"`
+	Seed     int64     `json:"seed"`
+	Date     time.Time `json:"date"`
+	// Ticks is the time from the start to the finish, one tick is one second
+	Ticks int `json:"ticks"`
+	// Distance is the sailed distance from the start to the finish in metres
+	Distance float64 `json:"distance"`
+	Tacks    int     `json:"tacks"`
+	// Flags is how many marks were missed, rounded on the wrong side or hit
+	Flags int `json:"flags"`
+}
+
+// Time returns the time from the start to the finish
+func (r RaceResult) Time() time.Duration {
+	return time.Duration(r.Ticks) * time.Second
+}
+
+// better returns true if the result ranks above the other one: clean runs before flagged ones,
+// then the fastest and the shortest
+func (r RaceResult) better(other RaceResult) bool {
+	if (r.Flags == 0) != (other.Flags == 0) {
+		return r.Flags == 0
+	}
+	if r.Ticks != other.Ticks {
+		return r.Ticks < other.Ticks
+	}
+	return r.Distance < other.Distance
+}
+
+// Results are the finished runs of all the scenarios, kept in a local JSON file
+type Results struct {
+	Results []RaceResult `json:"results"`
+}
+
+// LoadResults reads JSON encoded results
+func LoadResults(reader io.Reader) (*Results, error) {
+	var r Results
+	if err := json.NewDecoder(reader).Decode(&r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Save writes the results as JSON
+func (r *Results) Save(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// Add adds a finished run
+func (r *Results) Add(result RaceResult) {
+	r.Results = append(r.Results, result)
+}
+
+// Leaderboard returns the runs of the scenario from the best to the worst
+func (r *Results) Leaderboard(scenario string) []RaceResult {
+	var board []RaceResult
+	for _, result := range r.Results {
+		if result.Scenario == scenario {
+			board = append(board, result)
+		}
+	}
+	sort.SliceStable(board, func(i, j int) bool { return board[i].better(board[j]) })
+	return board
+}
@@ -0,0 +1,41 @@
+package gosailing
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultsLeaderboard(t *testing.T) {
+	require := require.New(t)
+
+	date := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	results := &Results{}
+	results.Add(RaceResult{Player: "anna", Scenario: "upwind", Date: date, Ticks: 420, Distance: 1500})
+	results.Add(RaceResult{Player: "mart", Scenario: "upwind", Date: date, Ticks: 400, Distance: 1450, Flags: 1})
+	results.Add(RaceResult{Player: "mart", Scenario: "upwind", Date: date, Ticks: 410, Distance: 1480})
+	results.Add(RaceResult{Player: "anna", Scenario: "upwind", Date: date, Ticks: 410, Distance: 1470})
+	results.Add(RaceResult{Player: "anna", Scenario: "triangle", Date: date, Ticks: 300})
+
+	board := results.Leaderboard("upwind")
+	require.Len(board, 4)
+	// Clean runs rank before flagged ones, the shorter run breaks the tie on time
+	require.Equal("anna", board[0].Player)
+	require.Equal(1470.0, board[0].Distance)
+	require.Equal("mart", board[1].Player)
+	require.Equal(420, board[2].Ticks)
+	require.Equal(1, board[3].Flags)
+
+	require.Equal(6*time.Minute+50*time.Second, board[1].Time())
+
+	var buf bytes.Buffer
+	require.NoError(results.Save(&buf))
+	loaded, err := LoadResults(&buf)
+	require.NoError(err)
+	require.Equal(results.Leaderboard("triangle"), loaded.Leaderboard("triangle"))
+
+	_, err = LoadResults(bytes.NewBufferString("["))
+	require.Error(err)
+}
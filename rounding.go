@@ -0,0 +1,192 @@
+package gosailing
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// MarkHitRadius is how close the middle of the boat can get to a mark without hitting it, in pixels
+const MarkHitRadius = 6.0
+
+// RoundingStatus is how the end of a leg was passed
+type RoundingStatus int
+
+const (
+	RoundingPending RoundingStatus = iota
+	// Rounded is a mark rounded on the required side, a gate sailed through or the finish crossed
+	Rounded
+	// RoundedWrongSide is a mark rounded on the wrong side
+	RoundedWrongSide
+	// MarkMissed is a mark that was skipped by rounding the next one
+	MarkMissed
+)
+
+func (s RoundingStatus) String() string {
+	switch s {
+	case Rounded:
+		return "rounded"
+	case RoundedWrongSide:
+		return "wrong side"
+	case MarkMissed:
+		return "missed"
+	}
+	return "pending"
+}
+
+// MarkRounding is the result of a leg of the course
+type MarkRounding struct {
+	Name   string
+	Status RoundingStatus
+	// Side is the side of the boat that faced the mark, empty for gates and the finish
+	Side string
+	// Tick is the simulation tick when the mark was rounded
+	Tick int
+	// Hits is how many times the boat touched the mark on the leg
+	Hits int
+}
+
+// Flagged returns true if the rounding breaks the rules
+func (mr MarkRounding) Flagged() bool {
+	return mr.Status == RoundedWrongSide || mr.Status == MarkMissed || mr.Hits > 0
+}
+
+func (mr MarkRounding) String() string {
+	result := mr.Status.String()
+	if mr.Status == Rounded || mr.Status == RoundedWrongSide {
+		result = fmt.Sprintf("%s %s", formatTicks(mr.Tick), result)
+		if mr.Side != "" {
+			result += " to " + mr.Side
+		}
+	}
+	if mr.Hits > 0 {
+		result += ", hit the mark"
+	}
+	return fmt.Sprintf("%-14s %s", mr.Name, result)
+}
+
+// formatTicks formats simulation ticks as minutes and seconds, one tick is one second
+func formatTicks(tick int) string {
+	sign := ""
+	if tick < 0 {
+		sign = "-"
+		tick = -tick
+	}
+	d := time.Duration(tick) * time.Second
+	return fmt.Sprintf("%s%d:%02d", sign, int(d.Minutes()), int(d.Seconds())%60)
+}
+
+// RoundingTracker follows the track of a boat around the course and checks that the marks are
+// rounded in order and on the required side. A mark that is skipped is flagged as missed when
+// the boat rounds the next one.
+type RoundingTracker struct {
+	course    *Course
+	leg       int
+	roundings []MarkRounding
+}
+
+func NewRoundingTracker(course *Course) *RoundingTracker {
+	rt := &RoundingTracker{
+		course:    course,
+		roundings: make([]MarkRounding, course.Legs()),
+	}
+	for i := range rt.roundings {
+		rt.roundings[i].Name = course.LegName(i)
+	}
+	return rt
+}
+
+// Leg returns the leg the boat is sailing, equal to the number of legs after finishing
+func (rt *RoundingTracker) Leg() int {
+	return rt.leg
+}
+
+// Finished returns true after the boat has crossed the finish line
+func (rt *RoundingTracker) Finished() bool {
+	return rt.leg >= rt.course.Legs()
+}
+
+// Roundings returns the results of the legs that have been sailed
+func (rt *RoundingTracker) Roundings() []MarkRounding {
+	return rt.roundings[:min(rt.leg, len(rt.roundings))]
+}
+
+// LastRounding returns the result of the last leg that was sailed, false before the first mark
+func (rt *RoundingTracker) LastRounding() (MarkRounding, bool) {
+	if rt.leg == 0 {
+		return MarkRounding{}, false
+	}
+	return rt.roundings[rt.leg-1], true
+}
+
+// Update follows the boat sailing from x1, y1 to x2, y2 on the tick
+func (rt *RoundingTracker) Update(tick int, x1, y1, x2, y2 float64) {
+	if rt.Finished() {
+		return
+	}
+
+	if rt.hits(rt.leg, x1, y1, x2, y2) {
+		rt.roundings[rt.leg].Hits++
+	}
+
+	if side, ok := rt.course.Pass(rt.leg, x1, y1, x2, y2); ok {
+		rt.round(rt.leg, side, tick)
+		rt.leg++
+		return
+	}
+
+	// Rounding the next mark correctly means that this one was skipped
+	next := rt.leg + 1
+	if next >= rt.course.Legs() {
+		return
+	}
+	if side, ok := rt.course.Pass(next, x1, y1, x2, y2); ok && rt.requiredSide(next, side) {
+		rt.roundings[rt.leg].Status = MarkMissed
+		rt.round(next, side, tick)
+		rt.leg += 2
+	}
+}
+
+func (rt *RoundingTracker) round(leg int, side string, tick int) {
+	r := &rt.roundings[leg]
+	r.Side = side
+	r.Tick = tick
+	r.Status = Rounded
+	if !rt.requiredSide(leg, side) {
+		r.Status = RoundedWrongSide
+	}
+}
+
+func (rt *RoundingTracker) requiredSide(leg int, side string) bool {
+	m, ok := rt.course.Mark(leg)
+	return !ok || m.IsGate || m.Rounding == side
+}
+
+// hits returns true if sailing from x1, y1 to x2, y2 touches the mark of the leg, having been
+// clear of it at x1, y1
+func (rt *RoundingTracker) hits(leg int, x1, y1, x2, y2 float64) bool {
+	m, ok := rt.course.Mark(leg)
+	if !ok {
+		return false
+	}
+	marks := [][2]float64{{m.X, m.Y}}
+	if m.IsGate {
+		marks = append(marks, [2]float64{m.GateX, m.GateY})
+	}
+	for _, mark := range marks {
+		if math.Hypot(x1-mark[0], y1-mark[1]) >= MarkHitRadius && segmentDistance(mark[0], mark[1], x1, y1, x2, y2) < MarkHitRadius {
+			return true
+		}
+	}
+	return false
+}
+
+// segmentDistance returns the distance of the point px, py from the segment between a and b
+func segmentDistance(px, py, ax, ay, bx, by float64) float64 {
+	dx, dy := bx-ax, by-ay
+	f := 0.0
+	if length := dx*dx + dy*dy; length > 0 {
+		f = math.Max(0, math.Min(1, ((px-ax)*dx+(py-ay)*dy)/length))
+	}
+	return math.Hypot(ax+dx*f-px, ay+dy*f-py)
+}
@@ -0,0 +1,67 @@
+package gosailing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+// sail follows the boat along the path, one tick per point
+func sail(rt *RoundingTracker, path ...[2]float64) {
+	for i := 1; i < len(path); i++ {
+		rt.Update(i, path[i-1][0], path[i-1][1], path[i][0], path[i][1])
+	}
+}
+
+func TestRoundingTracker(t *testing.T) {
+	require := require.New(t)
+
+	course := WindwardLeewardCourse(500, 0, 600, 0, 1)
+	rt := NewRoundingTracker(course)
+
+	// Up the beat and around the windward mark anticlockwise, keeping it to port
+	sail(rt, [2]float64{500, 0}, [2]float64{520, 590}, [2]float64{510, 610}, [2]float64{490, 610})
+	require.Equal(1, rt.Leg())
+	last, ok := rt.LastRounding()
+	require.True(ok)
+	require.Equal(Rounded, last.Status)
+	require.Equal(datasource.RoundingPort, last.Side)
+	require.Equal(3, last.Tick)
+	require.False(last.Flagged())
+
+	// Through the gate, touching its left mark, and up to the finish
+	sail(rt, [2]float64{490, 610}, [2]float64{463, 100}, [2]float64{462, 80}, [2]float64{500, 490})
+	require.True(rt.Finished())
+
+	roundings := rt.Roundings()
+	require.Len(roundings, 3)
+	require.Equal(Rounded, roundings[1].Status)
+	require.Equal(1, roundings[1].Hits)
+	require.True(roundings[1].Flagged())
+	require.Equal("Finish", roundings[2].Name)
+	require.Equal(Rounded, roundings[2].Status)
+}
+
+func TestRoundingTrackerWrongSideAndMissed(t *testing.T) {
+	require := require.New(t)
+
+	course := TriangleCourse(500, 0, 600, 0, 1)
+	rt := NewRoundingTracker(course)
+
+	// Around the windward mark clockwise
+	sail(rt, [2]float64{500, 0}, [2]float64{480, 590}, [2]float64{490, 610}, [2]float64{510, 610})
+	last, _ := rt.LastRounding()
+	require.Equal(RoundedWrongSide, last.Status)
+	require.Equal(datasource.RoundingStarboard, last.Side)
+	require.Equal("Windward       0:03 wrong side to starboard", last.String())
+
+	// Straight down to the leeward mark, skipping the wing mark
+	sail(rt, [2]float64{510, 610}, [2]float64{490, 600}, [2]float64{490, 80}, [2]float64{510, 80}, [2]float64{510, 100})
+	require.Equal(3, rt.Leg())
+	roundings := rt.Roundings()
+	require.Equal(MarkMissed, roundings[1].Status)
+	require.Equal("Wing           missed", roundings[1].String())
+	require.Equal(Rounded, roundings[2].Status)
+	require.Equal(datasource.RoundingPort, roundings[2].Side)
+}
@@ -9,27 +9,48 @@ import (
 	"github.com/gopxl/pixel/v2/backends/opengl"
 	"github.com/gopxl/pixel/v2/ext/imdraw"
 	"github.com/gopxl/pixel/v2/ext/text"
+	"github.io/mpihlak/gosailing/datasource"
 	"golang.org/x/image/colornames"
 	"golang.org/x/image/font/basicfont"
 )
 
+// GameBoatLength is the length of the game boat in metres, for measuring the line bias in boat lengths
+const GameBoatLength = 10.0
+
 type SailRace struct {
 	raceCourse *RaceCourse
-	boat       *Boat
-	wind       WindShifter
-	track      *TrackPlotter
-	lastTack   time.Time
-	delayMs    int
-	paused     bool
-	started    bool
-	finished   bool
-	laylines   bool
-	race       *imdraw.IMDraw
+	course     *Course
+	leg        int
+	rounding   *RoundingTracker
+	start      *StartSequence
+	// startDistance is the sailed distance at the start
+	startDistance float64
+	tacks         int
+	leaderboard   []RaceResult
+	run           RaceResult
+	startBox      *StartingBox
+	finishBox     *StartingBox
+	boat          *Boat
+	wind          WindShifter
+	track         *TrackPlotter
+	lastTack      time.Time
+	delayMs       int
+	paused        bool
+	started       bool
+	finished      bool
+	laylines      bool
+	race          *imdraw.IMDraw
+	// tick is the simulation time, the boat advances by one pixel on every tick
+	tick  int
+	ghost *Ghost
+	// ghostRounding follows the ghost around the course for measuring the lead
+	ghostRounding *RoundingTracker
+	recording     []GhostPoint
 }
 
 func NewSailRace(markLocationX, markLocationY, boatLocationX, boatLocationY float64, windShifter WindShifter) *SailRace {
 	wd := windShifter.GetWindDirection()
-	return &SailRace{
+	sr := &SailRace{
 		raceCourse: NewRaceCourse(markLocationX, markLocationY, wd),
 		boat:       NewBoat(boatLocationX, boatLocationY, wd),
 		wind:       windShifter,
@@ -37,6 +58,96 @@ func NewSailRace(markLocationX, markLocationY, boatLocationX, boatLocationY floa
 		delayMs:    50,
 		laylines:   true,
 	}
+	sr.SetCourse(UpwindCourse(boatLocationX, boatLocationY, markLocationX, markLocationY, wd))
+	return sr
+}
+
+// SetCourse sets the course to race, the race starts on its first leg
+func (sr *SailRace) SetCourse(course *Course) {
+	sr.course = course
+	sr.raceCourse.SetMarks(course.Marks)
+
+	// Reading the wind would move it on, so the race stays on the same wind as any other run
+	wd := sr.raceCourse.windDirection
+	newLineBox := func(line CourseLine) *StartingBox {
+		box := NewStartingBox(line.BoatX, line.BoatY, line.PinX, line.PinY, wd)
+		box.SetLaylines(false)
+		box.SetShowWindDirection(false)
+		return box
+	}
+	sr.startBox = newLineBox(course.Start)
+	sr.finishBox = newLineBox(course.Finish)
+
+	sr.rounding = NewRoundingTracker(course)
+	sr.ghostRounding = NewRoundingTracker(course)
+	sr.setLeg(0)
+}
+
+// SetStartSequence starts the race with a five minute start sequence on the start line of the
+// course. The boat can be steered freely until it has started.
+func (sr *SailRace) SetStartSequence() {
+	courseX, courseY := sr.course.Target(0)
+	sr.start = NewStartSequence(sr.course.Start, courseX, courseY)
+	sr.startBox.SetLaylines(true)
+	sr.boat.SetFree(true)
+}
+
+// racing returns true once the boat has started and is sailing the course
+func (sr *SailRace) racing() bool {
+	return sr.start == nil || sr.start.Started()
+}
+
+// raceTick returns the ticks since the gun
+func (sr *SailRace) raceTick() int {
+	if sr.start == nil {
+		return sr.tick
+	}
+	return sr.tick - StartSequenceTicks
+}
+
+// setLeg starts the leg, the mark of the leg gets the laylines and the boat sails upwind or
+// downwind depending on where the mark is
+func (sr *SailRace) setLeg(leg int) {
+	sr.leg = leg
+	markX, markY := sr.course.Target(leg)
+	if sr.course.IsFinishLeg(leg) {
+		// The finish is laid at its pin end
+		markX, markY = sr.course.Finish.PinX, sr.course.Finish.PinY
+	}
+	sr.raceCourse.MarkX, sr.raceCourse.MarkY = markX, markY
+
+	x, y := sr.boat.GetXY()
+	downwind := sr.course.Downwind(leg, x, y, sr.raceCourse.windDirection)
+	sr.boat.SetDownwind(downwind)
+	sr.raceCourse.SetDownwind(downwind)
+}
+
+// remaining returns the distance still to sail on the course from x, y on the leg
+func (sr *SailRace) remaining(leg int, x, y float64) float64 {
+	if leg >= sr.course.Legs() {
+		return 0
+	}
+	return sr.course.Remaining(leg, x, y)
+}
+
+// SetGhost adds a ghost boat that sails the previous run in lock-step with the race, the ghost
+// waits at its start until the gun
+func (sr *SailRace) SetGhost(track *GhostTrack) {
+	sr.ghost = NewGhost(track)
+	sr.ghost.SetTick(sr.raceTick())
+}
+
+// Recording returns the run sailed so far from the gun as a ghost track that can be raced against
+func (sr *SailRace) Recording() *GhostTrack {
+	gun := min(sr.tick-sr.raceTick(), len(sr.recording))
+	points := make([]GhostPoint, len(sr.recording)-gun)
+	copy(points, sr.recording[gun:])
+	return &GhostTrack{Points: points, MetersPerPixel: GameMetersPerPixel}
+}
+
+func (sr *SailRace) record() {
+	x, y := sr.boat.GetXY()
+	sr.recording = append(sr.recording, GhostPoint{X: x, Y: y, Heading: sr.boat.heading})
 }
 
 func (sr *SailRace) StartRace() {
@@ -67,11 +178,21 @@ func (sr *SailRace) TackBoat() {
 	if sr.started {
 		if time.Since(sr.lastTack) > 500*time.Millisecond {
 			sr.boat.Tack()
+			if sr.racing() {
+				sr.tacks++
+			}
 			sr.lastTack = time.Now()
 		}
 	}
 }
 
+// SteerBoat turns the boat by the degrees, positive to the right, before it has started
+func (sr *SailRace) SteerBoat(degrees float64) {
+	if sr.started && !sr.paused {
+		sr.boat.Steer(degrees)
+	}
+}
+
 func (sr *SailRace) ToggleLaylines() {
 	sr.boat.ToggleLaylines()
 	sr.raceCourse.ToggleLaylines()
@@ -89,29 +210,68 @@ func (sr *SailRace) Update(win *opengl.Window) {
 	windowBounds := win.Bounds()
 	topLeftY := windowBounds.H()
 
-	currentBoatX, currentBoatY := sr.boat.GetXY()
+	if sr.started && !sr.paused && !sr.finished {
+		if sr.tick == 0 {
+			sr.record()
+		}
+		previousX, previousY := sr.boat.GetXY()
+		sr.track.PlotLocation(previousX, previousY)
+		sr.boat.Advance()
+		sr.tick++
+		sr.record()
 
-	// TODO: refactor the finishing condition so that it also works on non-vertical courses
+		currentX, currentY := sr.boat.GetXY()
+		if sr.racing() {
+			sr.rounding.Update(sr.raceTick(), previousX, previousY, currentX, currentY)
+			if sr.rounding.Finished() {
+				sr.finished = true
+			} else if sr.rounding.Leg() != sr.leg {
+				sr.setLeg(sr.rounding.Leg())
+			}
+		} else {
+			sr.start.Update(sr.tick, previousX, previousY, currentX, currentY)
+			if sr.start.Started() {
+				sr.startDistance = sr.boat.GetSailedDistance()
+				sr.boat.SetFree(false)
+				sr.setLeg(sr.leg)
+			}
+		}
 
-	if sr.started && !sr.paused && currentBoatY <= sr.raceCourse.MarkY {
-		sr.track.PlotLocation(sr.boat.GetXY())
-		sr.boat.Advance()
+		if sr.ghost != nil {
+			ghostX, ghostY := sr.ghost.GetXY()
+			sr.ghost.SetTick(sr.raceTick())
+			newGhostX, newGhostY := sr.ghost.GetXY()
+			if sr.raceTick() > 0 {
+				sr.ghostRounding.Update(sr.raceTick(), ghostX, ghostY, newGhostX, newGhostY)
+			}
+		}
 		windDirection := sr.wind.GetWindDirection()
 		sr.boat.SetWindDirection(windDirection)
 		sr.raceCourse.SetWindDirection(windDirection)
-	} else if currentBoatY > sr.raceCourse.MarkY {
-		sr.finished = true
+		sr.startBox.SetWindDirection(windDirection)
 	}
 
+	currentBoatX, currentBoatY := sr.boat.GetXY()
+
 	basicAtlas := text.NewAtlas(basicfont.Face7x13, text.ASCII)
 
 	if sr.started {
 		basicTxt := text.New(pixel.V(10, topLeftY-25), basicAtlas)
 		basicTxt.Color = colornames.Black
 
-		distanceToMark := math.Hypot(currentBoatX-sr.raceCourse.MarkX, currentBoatY-sr.raceCourse.MarkY)
+		targetX, targetY := sr.course.Target(sr.leg)
+		distanceToMark := math.Hypot(currentBoatX-targetX, currentBoatY-targetY)
 		fmt.Fprintf(basicTxt, "Sailed distance:  %.2f\n", sr.boat.GetSailedDistance())
+		fmt.Fprintf(basicTxt, "Leg %d/%d %s\n", sr.leg+1, sr.course.Legs(), sr.course.LegName(sr.leg))
 		fmt.Fprintf(basicTxt, "Distance to mark: %.2f\n", distanceToMark)
+		if sr.start != nil {
+			sr.printStart(basicTxt, currentBoatX, currentBoatY)
+		}
+		if last, ok := sr.rounding.LastRounding(); ok && last.Flagged() && !sr.finished {
+			basicTxt.Color = colornames.Red
+			fmt.Fprintln(basicTxt, last)
+			basicTxt.Color = colornames.Black
+		}
 
 		twd := -sr.raceCourse.windDirection
 		if twd < 0 {
@@ -123,24 +283,51 @@ func (sr *SailRace) Update(win *opengl.Window) {
 			hdg += 360
 		}
 		fmt.Fprintf(basicTxt, "HDG: %03.0f\n", hdg)
+		if sr.ghost != nil {
+			// Positive when we have less of the course left to sail than the ghost
+			ghostX, ghostY := sr.ghost.GetXY()
+			ghostRemaining := sr.remaining(sr.ghostRounding.Leg(), ghostX, ghostY)
+			lead := (ghostRemaining - sr.remaining(sr.rounding.Leg(), currentBoatX, currentBoatY)) * sr.ghost.MetersPerPixel()
+			if lead >= 0 {
+				fmt.Fprintf(basicTxt, "Ahead of ghost by %.0f m\n", lead)
+			} else {
+				fmt.Fprintf(basicTxt, "Behind ghost by %.0f m\n", -lead)
+			}
+		}
 		basicTxt.Draw(win, pixel.IM.Scaled(basicTxt.Orig, 2))
 
 		if sr.finished {
-			textX := windowBounds.Center().X - 100
-			textY := windowBounds.Center().Y
+			textX := windowBounds.Center().X - 150
+			textY := windowBounds.Center().Y + 150
 
 			basicTxt := text.New(pixel.V(textX, textY), basicAtlas)
 
 			basicTxt.Color = colornames.Darkblue
-			fmt.Fprintf(basicTxt, "TOTAL DISTANCE: %.2f\n", sr.boat.GetSailedDistance()+distanceToMark)
-			if currentBoatX < sr.raceCourse.MarkX {
-				basicTxt.Color = colornames.Red
-				fmt.Fprintln(basicTxt, "Wrong side of the mark!")
+			fmt.Fprintf(basicTxt, "DISTANCE: %s\n", formatDistance(sr.Result().Distance))
+			fmt.Fprintf(basicTxt, "TIME: %s\n", formatTicks(sr.raceTick()))
+			if sr.start != nil {
+				fmt.Fprintf(basicTxt, "Start: %s\n", sr.start.Score())
+			}
+			for _, r := range sr.rounding.Roundings() {
+				basicTxt.Color = colornames.Darkblue
+				if r.Flagged() {
+					basicTxt.Color = colornames.Red
+				}
+				fmt.Fprintln(basicTxt, r)
 			}
 			basicTxt.Draw(win, pixel.IM.Scaled(basicTxt.Orig, 2))
+
+			if sr.leaderboard != nil {
+				sr.drawLeaderboard(win, basicAtlas)
+			}
 		}
 	}
 
+	if sr.ghost != nil {
+		sr.ghost.Drawable().Draw(win)
+	}
+	sr.startBox.Drawable().Draw(win)
+	sr.finishBox.Drawable().Draw(win)
 	sr.boat.Drawable().Draw(win)
 	sr.raceCourse.Drawable().Draw(win)
 	sr.track.Drawable().Draw(win)
@@ -153,6 +340,7 @@ func (sr *SailRace) Update(win *opengl.Window) {
 			"Press SPACE to start or pause",
 			"'q' quits",
 			"'t' tacks'",
+			"'left' and 'right' steer before the start",
 			"'r' restarts'",
 			"'l' toggle laylines",
 			"'w' toggle wind",
@@ -168,6 +356,16 @@ func (sr *SailRace) Update(win *opengl.Window) {
 		basicTxt.Draw(win, pixel.IM.Scaled(basicTxt.Orig, 2))
 	}
 
+	if sr.start != nil {
+		if signal := sr.start.Signal(sr.tick); signal != "" && sr.started {
+			basicTxt := text.New(windowBounds.Center().Add(pixel.V(0, 150)), basicAtlas)
+			basicTxt.Dot.X -= basicTxt.BoundsOf(signal).W() / 2
+			basicTxt.Color = colornames.Darkblue
+			fmt.Fprintln(basicTxt, signal)
+			basicTxt.Draw(win, pixel.IM.Scaled(basicTxt.Orig, 3))
+		}
+	}
+
 	if sr.paused {
 		textX := windowBounds.Center().X
 		textY := windowBounds.Center().Y
@@ -180,3 +378,42 @@ func (sr *SailRace) Update(win *opengl.Window) {
 		basicTxt.Draw(win, pixel.IM.Scaled(basicTxt.Orig, 2))
 	}
 }
+
+// printStart shows the countdown, time to line and time to burn before the gun, and the start
+// after it
+func (sr *SailRace) printStart(txt *text.Text, x, y float64) {
+	if !sr.start.Gun(sr.tick) {
+		fmt.Fprintf(txt, "Start in %s\n", formatTicks(sr.start.TicksToGun(sr.tick)))
+		fmt.Fprintf(txt, "Time to line: %s\n", formatTicks(int(math.Ceil(sr.start.TimeToLine(x, y)))))
+		fmt.Fprintf(txt, "Time to burn: %s\n", formatTicks(int(math.Floor(sr.start.TimeToBurn(sr.tick, x, y)))))
+		sr.printLineBias(txt)
+		return
+	}
+
+	fmt.Fprintf(txt, "Start: %s\n", sr.start.Score())
+	switch {
+	case sr.start.MustReturn():
+		txt.Color = colornames.Red
+		fmt.Fprintln(txt, "OCS, return behind the line")
+		txt.Color = colornames.Black
+	case !sr.start.Started():
+		fmt.Fprintln(txt, "Cross the line to start")
+	}
+}
+
+// printLineBias shows the bias of the start line and how much the last wind shift changed it
+func (sr *SailRace) printLineBias(txt *text.Text) {
+	bias := sr.startBox.Bias()
+	if end := sr.startBox.FavouredEnd(); end == datasource.FavouredSquare {
+		fmt.Fprintln(txt, "Line bias: square")
+	} else {
+		lengths := math.Abs(sr.startBox.BiasDistance()) * GameMetersPerPixel / GameBoatLength
+		fmt.Fprintf(txt, "Line bias: %.0f deg %s end, %.1f lengths\n", math.Abs(bias), end, lengths)
+	}
+
+	if shifts := sr.startBox.BiasShifts(); len(shifts) > 1 {
+		last := shifts[len(shifts)-1]
+		change := last.Bias - shifts[len(shifts)-2].Bias
+		fmt.Fprintf(txt, "Last shift: %+.0f deg bias\n", change)
+	}
+}
@@ -0,0 +1,151 @@
+package gosailing
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"slices"
+)
+
+// scenarioCodeVersion is the first byte of the codes, bumped when the format changes
+const scenarioCodeVersion = 1
+
+// windPeriod is the period of the oscillating wind of the scenarios
+const windPeriod = 10
+
+// Scenario is the setup of a race: the course and the wind. A scenario is shared as a short code
+// and races with the same code are sailed in the same conditions.
+type Scenario struct {
+	// Course is one of CoursePresets
+	Course        string
+	Laps          int
+	StartSequence bool
+	WindDirection float64
+	WindAmplitude float64
+	// WindShiftRate is how many degrees the wind turns on every tick
+	WindShiftRate float64
+	// WindFile is the name of the wind data file to replay, the oscillating wind is used without one
+	WindFile string
+	Seed     int64
+}
+
+func (s Scenario) String() string {
+	course := s.Course
+	if s.Course != "upwind" {
+		course = fmt.Sprintf("%s x%d", s.Course, s.Laps)
+	}
+	if s.StartSequence {
+		course += " with start"
+	}
+	wind := fmt.Sprintf("wind %.0f/%.0f/%.2f", s.WindDirection, s.WindAmplitude, s.WindShiftRate)
+	if s.WindFile != "" {
+		wind = "wind " + s.WindFile
+	}
+	return fmt.Sprintf("%s, %s, seed %d", course, wind, s.Seed)
+}
+
+// Validate checks that the scenario can be raced and encoded
+func (s Scenario) Validate() error {
+	switch {
+	case !slices.Contains(CoursePresets, s.Course):
+		return fmt.Errorf("unknown course: %s", s.Course)
+	case s.Laps < 1 || s.Laps > math.MaxUint8:
+		return fmt.Errorf("invalid number of laps: %d", s.Laps)
+	case len(s.WindFile) > math.MaxUint8:
+		return fmt.Errorf("wind file name is too long: %s", s.WindFile)
+	}
+	return nil
+}
+
+// NewWindShifter creates the wind of the scenario, windData is the path of the wind file
+func (s Scenario) NewWindShifter(windData string) WindShifter {
+	if s.WindFile != "" {
+		return NewReplayShifter(windData, s.Seed)
+	}
+	ws := NewOscillatingWindShifter(s.WindDirection, s.WindAmplitude, windPeriod, s.WindShiftRate)
+	ws.SetPhase(s.Seed)
+	return ws
+}
+
+// Code encodes the scenario to a short URL safe code. The angles are kept to a hundredth of a
+// degree and the upwind course is always one lap. The byte after the flags is reserved for the
+// type of the boat and is zero for now.
+func (s Scenario) Code() (string, error) {
+	if err := s.Validate(); err != nil {
+		return "", err
+	}
+
+	laps := s.Laps
+	if s.Course == "upwind" {
+		laps = 1
+	}
+	var flags byte
+	if s.StartSequence {
+		flags |= 1
+	}
+	buf := []byte{
+		scenarioCodeVersion,
+		byte(slices.Index(CoursePresets, s.Course)),
+		byte(laps),
+		flags,
+		0,
+	}
+	buf = binary.AppendVarint(buf, s.Seed)
+	for _, v := range []float64{s.WindDirection, s.WindAmplitude, s.WindShiftRate} {
+		buf = binary.AppendVarint(buf, int64(math.Round(v*100)))
+	}
+	buf = append(buf, byte(len(s.WindFile)))
+	buf = append(buf, s.WindFile...)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(crc32.ChecksumIEEE(buf)))
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+var errInvalidScenarioCode = errors.New("invalid scenario code")
+
+// ParseScenarioCode decodes a scenario code
+func ParseScenarioCode(code string) (Scenario, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(code)
+	if err != nil || len(buf) < 7 {
+		return Scenario{}, errInvalidScenarioCode
+	}
+	data, checksum := buf[:len(buf)-2], binary.BigEndian.Uint16(buf[len(buf)-2:])
+	if uint16(crc32.ChecksumIEEE(data)) != checksum {
+		return Scenario{}, errInvalidScenarioCode
+	}
+	if data[0] != scenarioCodeVersion {
+		return Scenario{}, fmt.Errorf("unsupported scenario code version %d", data[0])
+	}
+	if int(data[1]) >= len(CoursePresets) || data[4] != 0 {
+		return Scenario{}, errInvalidScenarioCode
+	}
+
+	s := Scenario{
+		Course:        CoursePresets[data[1]],
+		Laps:          int(data[2]),
+		StartSequence: data[3]&1 != 0,
+	}
+	rest := data[5:]
+	readVarint := func() int64 {
+		v, n := binary.Varint(rest)
+		if n <= 0 {
+			err = errInvalidScenarioCode
+			return 0
+		}
+		rest = rest[n:]
+		return v
+	}
+	s.Seed = readVarint()
+	s.WindDirection = float64(readVarint()) / 100
+	s.WindAmplitude = float64(readVarint()) / 100
+	s.WindShiftRate = float64(readVarint()) / 100
+	if err != nil || len(rest) < 1 || len(rest) != 1+int(rest[0]) {
+		return Scenario{}, errInvalidScenarioCode
+	}
+	s.WindFile = string(rest[1:])
+
+	return s, s.Validate()
+}
@@ -0,0 +1,77 @@
+package gosailing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScenarioCode(t *testing.T) {
+	require := require.New(t)
+
+	scenarios := []Scenario{
+		{Course: "upwind", Laps: 1, WindDirection: 0, WindAmplitude: 10, WindShiftRate: 0.1, Seed: 42},
+		{Course: "triangle", Laps: 2, StartSequence: true, WindDirection: -12.5, WindAmplitude: 7.25, WindShiftRate: 0.05, Seed: 987654},
+		{Course: "windward-leeward", Laps: 3, WindFile: "race-2024-06-01.csv", Seed: 7},
+	}
+	for _, s := range scenarios {
+		code, err := s.Code()
+		require.NoError(err)
+		require.Less(len(code), 64)
+
+		parsed, err := ParseScenarioCode(code)
+		require.NoError(err)
+		require.Equal(s, parsed)
+	}
+}
+
+func TestScenarioCodeUpwindLaps(t *testing.T) {
+	require := require.New(t)
+
+	// The upwind course has a single leg, so the laps don't change the race or its code
+	one, err := Scenario{Course: "upwind", Laps: 1, Seed: 42}.Code()
+	require.NoError(err)
+	three, err := Scenario{Course: "upwind", Laps: 3, Seed: 42}.Code()
+	require.NoError(err)
+	require.Equal(one, three)
+
+	parsed, err := ParseScenarioCode(three)
+	require.NoError(err)
+	require.Equal(1, parsed.Laps)
+}
+
+func TestScenarioCodeInvalid(t *testing.T) {
+	require := require.New(t)
+
+	code, err := Scenario{Course: "upwind", Laps: 1, WindAmplitude: 10, Seed: 42}.Code()
+	require.NoError(err)
+
+	// Changing any character breaks the checksum
+	tampered := []byte(code)
+	tampered[3] ^= 1
+	_, err = ParseScenarioCode(string(tampered))
+	require.Error(err)
+
+	_, err = ParseScenarioCode(code[:len(code)-2])
+	require.Error(err)
+	_, err = ParseScenarioCode("not a code!")
+	require.Error(err)
+	_, err = ParseScenarioCode("")
+	require.Error(err)
+}
+
+func TestScenarioValidate(t *testing.T) {
+	require := require.New(t)
+
+	s := Scenario{Course: "upwind", Laps: 1}
+	require.NoError(s.Validate())
+
+	s.Course = "olympic"
+	require.Error(s.Validate())
+	_, err := s.Code()
+	require.Error(err)
+
+	s.Course = "upwind"
+	s.Laps = 0
+	require.Error(s.Validate())
+}
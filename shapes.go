@@ -21,9 +21,20 @@ func DrawFlag(canvas *imdraw.IMDraw, x, y float64) {
 	canvas.Circle(2, 2)
 }
 
+// Translucent returns the color with the given opacity between 0 and 1. The color
+// components are premultiplied by the alpha as expected by the blending.
+func Translucent(c color.RGBA, opacity float64) color.RGBA {
+	return color.RGBA{
+		R: uint8(float64(c.R) * opacity),
+		G: uint8(float64(c.G) * opacity),
+		B: uint8(float64(c.B) * opacity),
+		A: uint8(float64(c.A) * opacity),
+	}
+}
+
 // DrawBoat draws a little triangle for the boat
-func DrawBoat(canvas *imdraw.IMDraw, x, y, heading float64) {
-	canvas.Color = colornames.Darkblue
+func DrawBoat(canvas *imdraw.IMDraw, x, y, heading float64, color color.RGBA) {
+	canvas.Color = color
 	// bow
 	bowX, bowY := RotatePoint(x, y+7.5, x, y, heading)
 	canvas.Push(pixel.V(bowX, bowY))
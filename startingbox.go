@@ -1,11 +1,27 @@
 package gosailing
 
 import (
+	"math"
+
 	"github.com/gopxl/pixel/v2"
 	"github.com/gopxl/pixel/v2/ext/imdraw"
+	"github.io/mpihlak/gosailing/datasource"
 	"golang.org/x/image/colornames"
 )
 
+const (
+	// biasShiftThreshold is how many degrees the wind has to move for it to count as a shift
+	biasShiftThreshold = 2.0
+	// maxBiasShifts is how many of the latest shifts are kept
+	maxBiasShifts = 10
+)
+
+// BiasShift is the bias of the line after a shift of the wind
+type BiasShift struct {
+	WindDirection float64
+	Bias          float64
+}
+
 type StartingBox struct {
 	BoatEndX          float64
 	BoatEndY          float64
@@ -14,6 +30,7 @@ type StartingBox struct {
 	windDirection     float64
 	laylines          bool
 	showWindDirection bool
+	shifts            []BiasShift
 	canvas            *imdraw.IMDraw
 }
 
@@ -34,6 +51,38 @@ func NewStartingBox(boatX, boatY, pinX, pinY, windDirection float64) *StartingBo
 // North is 0 and is straight up.
 func (rc *StartingBox) SetWindDirection(direction float64) {
 	rc.windDirection = direction
+
+	n := len(rc.shifts)
+	if n == 0 || math.Abs(datasource.NormalizeAngle(direction-rc.shifts[n-1].WindDirection)) >= biasShiftThreshold {
+		rc.shifts = append(rc.shifts, BiasShift{WindDirection: direction, Bias: rc.Bias()})
+		if len(rc.shifts) > maxBiasShifts {
+			rc.shifts = rc.shifts[1:]
+		}
+	}
+}
+
+// Bias returns how many degrees the line is turned from square to the wind, positive when the
+// committee boat end is favoured
+func (rc *StartingBox) Bias() float64 {
+	// The bearing of the line on the screen, clockwise from up like the wind direction
+	bearing := math.Atan2(rc.BoatEndX-rc.PinEndX, rc.BoatEndY-rc.PinEndY) * 180 / math.Pi
+	return datasource.LineBias(bearing, rc.windDirection)
+}
+
+// BiasDistance returns how many pixels further upwind the committee boat end is than the pin
+func (rc *StartingBox) BiasDistance() float64 {
+	length := math.Hypot(rc.BoatEndX-rc.PinEndX, rc.BoatEndY-rc.PinEndY)
+	return datasource.BiasDistance(length, rc.Bias())
+}
+
+// FavouredEnd returns the end of the line that is upwind, or that the line is square
+func (rc *StartingBox) FavouredEnd() string {
+	return datasource.FavouredEnd(rc.Bias())
+}
+
+// BiasShifts returns the bias of the line after each of the latest wind shifts, oldest first
+func (rc *StartingBox) BiasShifts() []BiasShift {
+	return rc.shifts
 }
 
 func (rc *StartingBox) ToggleLaylines() {
@@ -44,11 +93,19 @@ func (rc *StartingBox) ToggleWindDirection() {
 	rc.showWindDirection = !rc.showWindDirection
 }
 
+func (rc *StartingBox) SetLaylines(laylines bool) {
+	rc.laylines = laylines
+}
+
+func (rc *StartingBox) SetShowWindDirection(show bool) {
+	rc.showWindDirection = show
+}
+
 func (rc *StartingBox) Drawable() *imdraw.IMDraw {
 	rc.canvas.Clear()
 
 	DrawFlag(rc.canvas, rc.PinEndX, rc.PinEndY)
-	DrawBoat(rc.canvas, rc.BoatEndX, rc.BoatEndY, 0)
+	DrawBoat(rc.canvas, rc.BoatEndX, rc.BoatEndY, 0, colornames.Darkblue)
 
 	if rc.laylines {
 		// Boat end laylines
@@ -58,13 +115,24 @@ func (rc *StartingBox) Drawable() *imdraw.IMDraw {
 		// Pin end laylines
 		LayLine(rc.canvas, rc.PinEndX, rc.PinEndY, -TackAngle+rc.windDirection, colornames.Red)
 		LayLine(rc.canvas, rc.PinEndX, rc.PinEndY, TackAngle+rc.windDirection, colornames.Green)
+	}
 
-		// Starting line
-		rc.canvas.Color = colornames.Blue
-		rc.canvas.Push(pixel.V(rc.PinEndX, rc.PinEndY), pixel.V(rc.BoatEndX, rc.BoatEndY))
-		rc.canvas.Line(2)
+	// Favoured end
+	if end := rc.FavouredEnd(); end != datasource.FavouredSquare {
+		favouredX, favouredY := rc.PinEndX, rc.PinEndY
+		if end == datasource.FavouredBoat {
+			favouredX, favouredY = rc.BoatEndX, rc.BoatEndY
+		}
+		rc.canvas.Color = colornames.Green
+		rc.canvas.Push(pixel.V(favouredX, favouredY))
+		rc.canvas.Circle(12, 2)
 	}
 
+	// Starting line
+	rc.canvas.Color = colornames.Blue
+	rc.canvas.Push(pixel.V(rc.PinEndX, rc.PinEndY), pixel.V(rc.BoatEndX, rc.BoatEndY))
+	rc.canvas.Line(2)
+
 	if rc.showWindDirection {
 		// Wind direction indicator
 		wdStartX := (rc.PinEndX + rc.BoatEndX) / 2
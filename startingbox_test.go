@@ -0,0 +1,32 @@
+package gosailing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+func TestStartingBoxBias(t *testing.T) {
+	require := require.New(t)
+
+	box := NewStartingBox(600, 200, 400, 200, 0)
+	require.InDelta(0, box.Bias(), 0.001)
+	require.Equal(datasource.FavouredSquare, box.FavouredEnd())
+
+	box.SetWindDirection(0)
+	box.SetWindDirection(1)
+	// The wind veers to the right, bringing the committee boat end upwind
+	box.SetWindDirection(10)
+	require.InDelta(10, box.Bias(), 0.001)
+	require.InDelta(34.73, box.BiasDistance(), 0.01)
+	require.Equal(datasource.FavouredBoat, box.FavouredEnd())
+
+	shifts := box.BiasShifts()
+	require.Len(shifts, 2)
+	require.InDelta(0, shifts[0].Bias, 0.001)
+	require.InDelta(10, shifts[1].Bias, 0.001)
+
+	box.SetWindDirection(-5)
+	require.Equal(datasource.FavouredPin, box.FavouredEnd())
+}
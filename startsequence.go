@@ -0,0 +1,169 @@
+package gosailing
+
+import (
+	"fmt"
+	"math"
+)
+
+// StartSequenceTicks is the length of the start sequence from the warning signal to the gun,
+// five minutes with one tick per second
+const StartSequenceTicks = 5 * 60
+
+// startSignals are the signals of the 5-4-1-0 start sequence, by the ticks left to the gun
+var startSignals = []struct {
+	ticksLeft int
+	name      string
+}{
+	{5 * 60, "Warning signal"},
+	{4 * 60, "Preparatory signal"},
+	{1 * 60, "One minute"},
+	{0, "Start!"},
+}
+
+// signalDuration is how many ticks the name of a signal is shown after it
+const signalDuration = 5
+
+// StartScore is how well the boat started, measured at the gun
+type StartScore struct {
+	// DistanceBehind is how far behind the line the boat was at the gun in metres
+	DistanceBehind float64
+	// LinePosition is where along the line the boat was, 0 at the pin and 1 at the committee boat
+	LinePosition float64
+	// OCS is set when the boat was on the course side of the line at the gun
+	OCS    bool
+	Points int
+}
+
+func (s StartScore) String() string {
+	if s.OCS {
+		return fmt.Sprintf("OCS, %.0f m over the line, %d points", s.DistanceBehind, s.Points)
+	}
+	return fmt.Sprintf("%.0f m behind, %.0f%% up the line, %d points", s.DistanceBehind, s.LinePosition*100, s.Points)
+}
+
+// StartSequence counts down to the gun and judges the start of the boat. A boat that is over
+// the line at the gun has to return behind it, or its extensions, and start again.
+type StartSequence struct {
+	line CourseLine
+	// courseSide is a point on the course side of the line
+	courseSideX float64
+	courseSideY float64
+	score       StartScore
+	judged      bool
+	returned    bool
+	started     bool
+	startTick   int
+}
+
+func NewStartSequence(line CourseLine, courseSideX, courseSideY float64) *StartSequence {
+	return &StartSequence{
+		line:        line,
+		courseSideX: courseSideX,
+		courseSideY: courseSideY,
+	}
+}
+
+// TicksToGun returns the ticks left to the gun at the tick, negative after the gun
+func (ss *StartSequence) TicksToGun(tick int) int {
+	return StartSequenceTicks - tick
+}
+
+// Signal returns the name of the signal made at most a few ticks before the tick, or empty
+func (ss *StartSequence) Signal(tick int) string {
+	left := ss.TicksToGun(tick)
+	for _, s := range startSignals {
+		if left <= s.ticksLeft && left > s.ticksLeft-signalDuration {
+			return s.name
+		}
+	}
+	return ""
+}
+
+// TimeToLine returns the ticks it takes to sail from x, y to the line at full speed
+func (ss *StartSequence) TimeToLine(x, y float64) float64 {
+	if ss.onCourseSide(x, y) {
+		return 0
+	}
+	return segmentDistance(x, y, ss.line.PinX, ss.line.PinY, ss.line.BoatX, ss.line.BoatY)
+}
+
+// TimeToBurn returns the ticks that the boat at x, y has to spare before it has to go for the line
+func (ss *StartSequence) TimeToBurn(tick int, x, y float64) float64 {
+	return float64(ss.TicksToGun(tick)) - ss.TimeToLine(x, y)
+}
+
+// Update follows the boat sailing from x1, y1 to x2, y2 on the tick
+func (ss *StartSequence) Update(tick int, x1, y1, x2, y2 float64) {
+	switch {
+	case ss.started || tick < StartSequenceTicks:
+		return
+	case !ss.judged:
+		ss.judge(x2, y2)
+		return
+	}
+
+	if ss.score.OCS && !ss.returned {
+		ss.returned = !ss.onCourseSide(x2, y2)
+		return
+	}
+	if ss.line.Crossed(x1, y1, x2, y2) && ss.onCourseSide(x2, y2) {
+		ss.started = true
+		ss.startTick = tick
+	}
+}
+
+// judge scores the boat at x, y at the gun
+func (ss *StartSequence) judge(x, y float64) {
+	ss.judged = true
+
+	lineX, lineY := ss.line.BoatX-ss.line.PinX, ss.line.BoatY-ss.line.PinY
+	length := math.Hypot(lineX, lineY)
+	ss.score.LinePosition = ((x-ss.line.PinX)*lineX + (y-ss.line.PinY)*lineY) / (length * length)
+	ss.score.DistanceBehind = math.Abs(cross(ss.line.PinX, ss.line.PinY, ss.line.BoatX, ss.line.BoatY, x, y)) / length * GameMetersPerPixel
+	ss.score.OCS = ss.onCourseSide(x, y)
+
+	if !ss.score.OCS {
+		// A point for every metre closer than 80 m and 20 more for being between the ends
+		points := max(0, 80-ss.score.DistanceBehind)
+		if ss.score.LinePosition >= 0 && ss.score.LinePosition <= 1 {
+			points += 20
+		}
+		ss.score.Points = int(math.Round(points))
+	}
+}
+
+// Gun returns true after the start signal
+func (ss *StartSequence) Gun(tick int) bool {
+	return tick >= StartSequenceTicks
+}
+
+// Started returns true when the boat has crossed the line after the gun, having returned first
+// if it was over early
+func (ss *StartSequence) Started() bool {
+	return ss.started
+}
+
+// StartTick returns the tick the boat started on
+func (ss *StartSequence) StartTick() int {
+	return ss.startTick
+}
+
+// MustReturn returns true for a boat that was over the line at the gun and hasn't returned yet
+func (ss *StartSequence) MustReturn() bool {
+	return ss.score.OCS && !ss.returned
+}
+
+// Score returns the score of the start, zero before the gun
+func (ss *StartSequence) Score() StartScore {
+	return ss.score
+}
+
+func (ss *StartSequence) onCourseSide(x, y float64) bool {
+	return !ss.behind(x, y)
+}
+
+// behind returns true if x, y is on the pre-start side of the line or exactly on it
+func (ss *StartSequence) behind(x, y float64) bool {
+	return cross(ss.line.PinX, ss.line.PinY, ss.line.BoatX, ss.line.BoatY, x, y)*
+		cross(ss.line.PinX, ss.line.PinY, ss.line.BoatX, ss.line.BoatY, ss.courseSideX, ss.courseSideY) <= 0
+}
@@ -0,0 +1,68 @@
+package gosailing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartSequenceCountdown(t *testing.T) {
+	require := require.New(t)
+
+	line := CourseLine{BoatX: 600, BoatY: 200, PinX: 400, PinY: 200}
+	ss := NewStartSequence(line, 500, 700)
+
+	require.Equal("Warning signal", ss.Signal(0))
+	require.Equal("", ss.Signal(30))
+	require.Equal("Preparatory signal", ss.Signal(60))
+	require.Equal("One minute", ss.Signal(242))
+	require.Equal("Start!", ss.Signal(300))
+	require.Equal("", ss.Signal(305))
+
+	require.InDelta(50, ss.TimeToLine(450, 150), 0.001)
+	require.InDelta(64.031, ss.TimeToLine(650, 160), 0.001)
+	require.InDelta(0, ss.TimeToLine(500, 210), 0.001)
+	require.InDelta(10, ss.TimeToBurn(240, 450, 150), 0.001)
+	require.Equal("-0:10", formatTicks(-10))
+}
+
+func TestStartSequenceGoodStart(t *testing.T) {
+	require := require.New(t)
+
+	ss := NewStartSequence(CourseLine{BoatX: 600, BoatY: 200, PinX: 400, PinY: 200}, 500, 700)
+	ss.Update(299, 450, 195, 450, 196)
+	require.False(ss.Gun(299))
+	ss.Update(300, 450, 196, 450, 197)
+	require.True(ss.Gun(300))
+	require.False(ss.Started())
+
+	score := ss.Score()
+	require.False(score.OCS)
+	require.InDelta(9, score.DistanceBehind, 0.001)
+	require.InDelta(0.25, score.LinePosition, 0.001)
+	require.Equal(91, score.Points)
+	require.Equal("9 m behind, 25% up the line, 91 points", score.String())
+
+	ss.Update(303, 450, 199, 450, 200.5)
+	require.True(ss.Started())
+	require.Equal(303, ss.StartTick())
+}
+
+func TestStartSequenceOCS(t *testing.T) {
+	require := require.New(t)
+
+	ss := NewStartSequence(CourseLine{BoatX: 600, BoatY: 200, PinX: 400, PinY: 200}, 500, 700)
+	ss.Update(300, 500, 201, 500, 202)
+	require.True(ss.Score().OCS)
+	require.Equal(0, ss.Score().Points)
+	require.True(ss.MustReturn())
+
+	// Sailing on doesn't count as a start until the boat has returned around the end of the line
+	ss.Update(301, 500, 202, 500, 203)
+	require.False(ss.Started())
+	ss.Update(320, 610, 201, 610, 199)
+	require.False(ss.MustReturn())
+	ss.Update(330, 590, 199, 590, 201)
+	require.True(ss.Started())
+	require.Equal(330, ss.StartTick())
+}
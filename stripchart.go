@@ -0,0 +1,172 @@
+package gosailing
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/gopxl/pixel/v2"
+	"github.com/gopxl/pixel/v2/ext/imdraw"
+	"github.io/mpihlak/gosailing/datasource"
+	"golang.org/x/image/colornames"
+)
+
+// ChartSeries is a channel of the log that can be plotted in a strip chart
+type ChartSeries struct {
+	Label  string
+	Format string
+	Value  func(p datasource.NavigationDataPoint) float64
+	// Direction series are unwrapped so that the plot doesn't jump when crossing north
+	Direction bool
+}
+
+// ChartSeriesList are the series of the strip chart panel from top to bottom
+var ChartSeriesList = []ChartSeries{
+	{"TWD", "%03.0f", func(p datasource.NavigationDataPoint) float64 { return p.TrueWindDirection }, true},
+	{"TWS", "%.1f", func(p datasource.NavigationDataPoint) float64 { return p.TrueWindSpeed }, false},
+	{"SOG", "%.2f", func(p datasource.NavigationDataPoint) float64 { return p.SpeedOverGround }, false},
+	{"STW", "%.2f", func(p datasource.NavigationDataPoint) float64 { return p.SpeedThroughWater }, false},
+	{"HEEL", "%.0f", func(p datasource.NavigationDataPoint) float64 { return p.Roll }, false},
+	{"VMG", "%.2f", func(p datasource.NavigationDataPoint) float64 {
+		return p.SpeedOverGround * math.Cos(toRadians(p.CourseOverGround-p.TrueWindDirection))
+	}, false},
+}
+
+// StripChart plots a series over the whole replay session, on the same time axis as the timeline
+type StripChart struct {
+	series ChartSeries
+	axis   *Timeline
+	times  []time.Time
+	values []float64
+	low    float64
+	high   float64
+	// line is the plot on the screen, with at most one point per pixel column
+	line   []pixel.Vec
+	canvas *imdraw.IMDraw
+}
+
+func NewStripChart(series ChartSeries, points []datasource.NavigationDataPoint, start, end time.Time, bounds pixel.Rect) *StripChart {
+	sc := &StripChart{
+		series: series,
+		axis:   NewTimeline(start, end, bounds),
+		times:  make([]time.Time, len(points)),
+		values: make([]float64, len(points)),
+		canvas: imdraw.New(nil),
+	}
+
+	for i, p := range points {
+		sc.times[i] = p.Timestamp
+		sc.values[i] = series.Value(p)
+		if series.Direction && i > 0 {
+			sc.values[i] = sc.values[i-1] + datasource.NormalizeAngle(sc.values[i]-sc.values[i-1])
+		}
+		if i == 0 || sc.values[i] < sc.low {
+			sc.low = sc.values[i]
+		}
+		if i == 0 || sc.values[i] > sc.high {
+			sc.high = sc.values[i]
+		}
+	}
+	if sc.high-sc.low < 1 {
+		middle := (sc.high + sc.low) / 2
+		sc.low, sc.high = middle-0.5, middle+0.5
+	}
+
+	lastX := math.Inf(-1)
+	for i := range sc.values {
+		x := sc.axis.XAt(sc.times[i])
+		if x-lastX < 1 && i < len(sc.values)-1 {
+			continue
+		}
+		sc.line = append(sc.line, pixel.V(x, sc.yAt(sc.values[i])))
+		lastX = x
+	}
+
+	return sc
+}
+
+// Contains returns true if the screen position is on the chart
+func (sc *StripChart) Contains(pos pixel.Vec) bool {
+	return sc.axis.Contains(pos)
+}
+
+// TimeAt returns the session time at the screen X coordinate
+func (sc *StripChart) TimeAt(x float64) time.Time {
+	return sc.axis.TimeAt(x)
+}
+
+// Bounds returns the screen area of the chart
+func (sc *StripChart) Bounds() pixel.Rect {
+	return sc.axis.Bounds()
+}
+
+// Label returns the name of the series
+func (sc *StripChart) Label() string {
+	return sc.series.Label
+}
+
+// Format returns the format of the values of the series
+func (sc *StripChart) Format() string {
+	return sc.series.Format
+}
+
+// ValueAt returns the value of the series at the time, interpolated between the points.
+// Directions are returned between 0 and 360.
+func (sc *StripChart) ValueAt(t time.Time) float64 {
+	i := sort.Search(len(sc.times), func(i int) bool { return sc.times[i].After(t) })
+	var v float64
+	switch {
+	case len(sc.values) == 0:
+		return 0
+	case i == 0:
+		v = sc.values[0]
+	case i == len(sc.values):
+		v = sc.values[i-1]
+	default:
+		f := float64(t.Sub(sc.times[i-1])) / float64(sc.times[i].Sub(sc.times[i-1]))
+		v = sc.values[i-1] + (sc.values[i]-sc.values[i-1])*f
+	}
+
+	if sc.series.Direction {
+		return datasource.NormalizeDirection(v)
+	}
+	return v
+}
+
+func (sc *StripChart) yAt(v float64) float64 {
+	bounds := sc.axis.Bounds()
+	return bounds.Min.Y + 2 + (v-sc.low)/(sc.high-sc.low)*(bounds.H()-4)
+}
+
+// Drawable draws the plot with a cursor at the current time and a line at the hover time,
+// unless the hover time is zero
+func (sc *StripChart) Drawable(current, hover time.Time) *imdraw.IMDraw {
+	sc.canvas.Clear()
+	bounds := sc.axis.Bounds()
+
+	sc.canvas.Color = colornames.White
+	sc.canvas.Push(bounds.Min, bounds.Max)
+	sc.canvas.Rectangle(0)
+
+	sc.canvas.Color = colornames.Steelblue
+	sc.canvas.Push(sc.line...)
+	sc.canvas.Line(1)
+
+	if !hover.IsZero() {
+		hoverX := sc.axis.XAt(hover)
+		sc.canvas.Color = colornames.Gray
+		sc.canvas.Push(pixel.V(hoverX, bounds.Min.Y), pixel.V(hoverX, bounds.Max.Y))
+		sc.canvas.Line(1)
+	}
+
+	cursorX := sc.axis.XAt(current)
+	sc.canvas.Color = colornames.Darkblue
+	sc.canvas.Push(pixel.V(cursorX, bounds.Min.Y), pixel.V(cursorX, bounds.Max.Y))
+	sc.canvas.Line(2)
+
+	sc.canvas.Color = colornames.Gray
+	sc.canvas.Push(bounds.Min, bounds.Max)
+	sc.canvas.Rectangle(1)
+
+	return sc.canvas
+}
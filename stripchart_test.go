@@ -0,0 +1,42 @@
+package gosailing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gopxl/pixel/v2"
+	"github.com/stretchr/testify/require"
+	"github.io/mpihlak/gosailing/datasource"
+)
+
+func TestStripChart(t *testing.T) {
+	require := require.New(t)
+
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	var points []datasource.NavigationDataPoint
+	for i, twd := range []float64{350, 355, 0, 5, 10} {
+		points = append(points, datasource.NavigationDataPoint{
+			Timestamp:         start.Add(time.Duration(i) * 10 * time.Second),
+			TrueWindDirection: twd,
+		})
+	}
+	end := points[len(points)-1].Timestamp
+
+	chart := NewStripChart(ChartSeriesList[0], points, start, end, pixel.R(0, 0, 400, 40))
+	require.Equal("TWD", chart.Label())
+
+	// The direction is unwrapped across north, so the plot rises steadily
+	require.InDelta(350.0, chart.low, 0.001)
+	require.InDelta(370.0, chart.high, 0.001)
+	for i := 1; i < len(chart.line); i++ {
+		require.Greater(chart.line[i].Y, chart.line[i-1].Y)
+	}
+
+	require.InDelta(352.5, chart.ValueAt(start.Add(5*time.Second)), 0.001)
+	require.InDelta(2.5, chart.ValueAt(start.Add(25*time.Second)), 0.001)
+	require.InDelta(350.0, chart.ValueAt(start.Add(-time.Minute)), 0.001)
+	require.InDelta(10.0, chart.ValueAt(end.Add(time.Minute)), 0.001)
+
+	require.True(chart.Contains(pixel.V(200, 20)))
+	require.Equal(start.Add(20*time.Second), chart.TimeAt(200))
+}
@@ -0,0 +1,106 @@
+package gosailing
+
+import (
+	"time"
+
+	"github.com/gopxl/pixel/v2"
+	"github.com/gopxl/pixel/v2/ext/imdraw"
+	"golang.org/x/image/colornames"
+)
+
+// Timeline is a bar that shows the whole replay session and the current position in it
+type Timeline struct {
+	start   time.Time
+	end     time.Time
+	bounds  pixel.Rect
+	markers []time.Time
+	// bookmarks are marked under the bar
+	bookmarks []time.Time
+	canvas    *imdraw.IMDraw
+}
+
+func NewTimeline(start, end time.Time, bounds pixel.Rect) *Timeline {
+	return &Timeline{
+		start:  start,
+		end:    end,
+		bounds: bounds,
+		canvas: imdraw.New(nil),
+	}
+}
+
+// Contains returns true if the screen position is on the timeline bar
+func (tl *Timeline) Contains(pos pixel.Vec) bool {
+	return tl.bounds.Contains(pos)
+}
+
+// TimeAt returns the session time at the given screen X coordinate
+func (tl *Timeline) TimeAt(x float64) time.Time {
+	fraction := (x - tl.bounds.Min.X) / tl.bounds.W()
+	fraction = min(1, max(0, fraction))
+	return tl.start.Add(time.Duration(fraction * float64(tl.end.Sub(tl.start))))
+}
+
+// XAt returns the screen X coordinate of the session time
+func (tl *Timeline) XAt(t time.Time) float64 {
+	duration := tl.end.Sub(tl.start)
+	if duration <= 0 {
+		return tl.bounds.Min.X
+	}
+	fraction := float64(t.Sub(tl.start)) / float64(duration)
+	fraction = min(1, max(0, fraction))
+	return tl.bounds.Min.X + fraction*tl.bounds.W()
+}
+
+// SetMarkers sets the times of the events that are marked on the timeline
+func (tl *Timeline) SetMarkers(markers []time.Time) {
+	tl.markers = markers
+}
+
+// SetBookmarks sets the times of the bookmarks that are marked under the timeline
+func (tl *Timeline) SetBookmarks(bookmarks []time.Time) {
+	tl.bookmarks = bookmarks
+}
+
+// Bounds returns the screen area of the timeline bar
+func (tl *Timeline) Bounds() pixel.Rect {
+	return tl.bounds
+}
+
+func (tl *Timeline) Drawable(current time.Time) *imdraw.IMDraw {
+	tl.canvas.Clear()
+
+	// Whole session
+	tl.canvas.Color = colornames.Lightgray
+	tl.canvas.Push(tl.bounds.Min, tl.bounds.Max)
+	tl.canvas.Rectangle(0)
+
+	// Played part of the session
+	cursorX := tl.XAt(current)
+	tl.canvas.Color = colornames.Steelblue
+	tl.canvas.Push(tl.bounds.Min, pixel.V(cursorX, tl.bounds.Max.Y))
+	tl.canvas.Rectangle(0)
+
+	tl.canvas.Color = colornames.Orangered
+	for _, marker := range tl.markers {
+		markerX := tl.XAt(marker)
+		tl.canvas.Push(pixel.V(markerX, tl.bounds.Max.Y), pixel.V(markerX, tl.bounds.Max.Y+5))
+		tl.canvas.Line(1)
+	}
+
+	tl.canvas.Color = colornames.Darkgoldenrod
+	for _, bookmark := range tl.bookmarks {
+		bookmarkX := tl.XAt(bookmark)
+		tl.canvas.Push(pixel.V(bookmarkX, tl.bounds.Min.Y), pixel.V(bookmarkX, tl.bounds.Min.Y-6))
+		tl.canvas.Line(2)
+	}
+
+	tl.canvas.Color = colornames.Darkblue
+	tl.canvas.Push(pixel.V(cursorX, tl.bounds.Min.Y-3), pixel.V(cursorX, tl.bounds.Max.Y+3))
+	tl.canvas.Line(3)
+
+	tl.canvas.Color = colornames.Gray
+	tl.canvas.Push(tl.bounds.Min, tl.bounds.Max)
+	tl.canvas.Rectangle(1)
+
+	return tl.canvas
+}
@@ -1,6 +1,7 @@
 package gosailing
 
 import (
+	"image/color"
 	"math"
 
 	"github.com/gopxl/pixel/v2"
@@ -10,6 +11,7 @@ import (
 
 type TrackPlotter struct {
 	canvas   *imdraw.IMDraw
+	color    color.RGBA
 	plottedX float64
 	plottedY float64
 }
@@ -18,14 +20,19 @@ func NewTrackPlotter(x, y float64) *TrackPlotter {
 	return &TrackPlotter{
 		plottedX: x,
 		plottedY: y,
+		color:    colornames.Blueviolet,
 		canvas:   imdraw.New(nil),
 	}
 }
 
+func (tp *TrackPlotter) SetColor(color color.RGBA) {
+	tp.color = color
+}
+
 func (tp *TrackPlotter) PlotLocation(x, y float64) {
 	distance := math.Hypot(tp.plottedX-x, tp.plottedY-y)
 	if distance > 5 {
-		tp.canvas.Color = colornames.Blueviolet
+		tp.canvas.Color = tp.color
 		tp.canvas.Push(pixel.V(tp.plottedX, tp.plottedY))
 		tp.canvas.Circle(1, 1)
 		tp.plottedX = x
@@ -37,6 +44,13 @@ func (tp *TrackPlotter) Clear() {
 	tp.canvas.Clear()
 }
 
+// Restart clears the track and starts plotting it again from the location
+func (tp *TrackPlotter) Restart(x, y float64) {
+	tp.canvas.Clear()
+	tp.plottedX = x
+	tp.plottedY = y
+}
+
 func (tp *TrackPlotter) Drawable() *imdraw.IMDraw {
 	return tp.canvas
 }
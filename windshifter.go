@@ -20,7 +20,9 @@ type ReplayWindShifter struct {
 	pos            float64
 }
 
-func NewReplayShifter(fileName string) *ReplayWindShifter {
+// NewReplayShifter replays the wind directions of the file from a random position picked by the seed,
+// seed 0 starts from the beginning of the file
+func NewReplayShifter(fileName string, seed int64) *ReplayWindShifter {
 	f, err := os.Open(fileName)
 	if err != nil {
 		panic(err)
@@ -67,10 +69,11 @@ func NewReplayShifter(fileName string) *ReplayWindShifter {
 		windDirections[i] -= medianWind
 	}
 
-	return &ReplayWindShifter{
-		windDirections: windDirections,
-		pos:            rand.Float64() * float64(len(windDirections)),
+	ws := &ReplayWindShifter{windDirections: windDirections}
+	if seed != 0 {
+		ws.pos = rand.New(rand.NewSource(seed)).Float64() * float64(len(windDirections))
 	}
+	return ws
 }
 
 // TODO: Use the commonly understood wind direction, ie. where is it blowing from
@@ -100,6 +103,15 @@ func NewOscillatingWindShifter(baseDirection, amplitude, period, shiftRate float
 	}
 }
 
+// SetPhase starts the oscillation at a random point of its period picked by the seed, seed 0
+// starts from the beginning of the period
+func (ws *OscillatingWindShifter) SetPhase(seed int64) {
+	ws.clock = 0
+	if seed != 0 {
+		ws.clock = rand.New(rand.NewSource(seed)).Float64() * ws.period
+	}
+}
+
 func (ws *OscillatingWindShifter) GetWindDirection() float64 {
 	shift := ws.amplitude * math.Sin(2*math.Pi*ws.clock/ws.period)
 	ws.clock += 0.05